@@ -19,7 +19,7 @@ func main() {
 	defer func() {
 		if err := recover(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error:\n%s\n", err)
-			os.Exit(1)
+			os.Exit(cli.ExitCodeError)
 		}
 	}()
 	cli := cli.New(os.Stdout, os.Stderr, version)
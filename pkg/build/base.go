@@ -0,0 +1,140 @@
+package build
+
+import (
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+)
+
+// getBase loads the base image from s, which is one of
+//
+//   - a reference of a remote image, pinned by digest or not
+//   - a directory of an OCI image layout, optionally followed by @digest
+//     to select an image when the layout has several
+//   - a tarball written by docker save or jctl
+//
+// Local sources let builds work without network access. The result is a
+// v1.ImageIndex when s refers to a multi-platform image.
+func getBase(s string) (Result, error) {
+	p, dig := s, ""
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		p, dig = s[:i], s[i+1:]
+	}
+	if info, err := os.Stat(p); err == nil {
+		if info.IsDir() {
+			return layoutBase(p, dig)
+		}
+		if dig != "" {
+			return nil, errors.Errorf("digest cannot be specified for tarball, path: %s", p)
+		}
+		return tarball.ImageFromPath(p, nil)
+	}
+
+	ref, err := name.ParseReference(s)
+	if err != nil {
+		return nil, err
+	}
+	desc, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return nil, err
+	}
+	if desc.MediaType.IsIndex() {
+		return desc.ImageIndex()
+	}
+	return desc.Image()
+}
+
+func layoutBase(p, dig string) (Result, error) {
+	idx, err := layout.ImageIndexFromPath(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read OCI image layout, path: %s", p)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	if dig != "" {
+		h, err := v1.NewHash(dig)
+		if err != nil {
+			return nil, err
+		}
+		for _, desc := range m.Manifests {
+			if desc.Digest == h {
+				return fromIndex(idx, desc)
+			}
+		}
+		return nil, errors.Errorf("digest %s not found in OCI image layout, path: %s", dig, p)
+	}
+
+	if len(m.Manifests) != 1 {
+		return nil, errors.Errorf("OCI image layout has %d manifests, select one with %s@sha256:..., path: %s", len(m.Manifests), p, p)
+	}
+	return fromIndex(idx, m.Manifests[0])
+}
+
+func fromIndex(idx v1.ImageIndex, desc v1.Descriptor) (Result, error) {
+	if desc.MediaType.IsIndex() {
+		return idx.ImageIndex(desc.Digest)
+	}
+	return idx.Image(desc.Digest)
+}
+
+// platformImage picks the image of platform from base. A single image is
+// accepted only when it is built for platform.
+func platformImage(base Result, platform v1.Platform) (v1.Image, error) {
+	if idx, ok := base.(v1.ImageIndex); ok {
+		m, err := idx.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+		for _, desc := range m.Manifests {
+			if desc.Platform != nil && desc.Platform.Satisfies(platform) {
+				return idx.Image(desc.Digest)
+			}
+		}
+		return nil, errors.Errorf("base image has no manifest for %s", platform)
+	}
+
+	img := base.(v1.Image)
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if p := cf.Platform(); p == nil || !p.Satisfies(platform) {
+		return nil, errors.Errorf("base image is not a multi-platform image and does not support %s", platform)
+	}
+	return img, nil
+}
+
+// basePlatforms lists the platforms of base.
+func basePlatforms(base Result) ([]v1.Platform, error) {
+	idx, ok := base.(v1.ImageIndex)
+	if !ok {
+		cf, err := base.(v1.Image).ConfigFile()
+		if err != nil {
+			return nil, err
+		}
+		return []v1.Platform{{OS: cf.OS, Architecture: cf.Architecture, Variant: cf.Variant}}, nil
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	var platforms []v1.Platform
+	for _, desc := range m.Manifests {
+		// Skip attestations and other artifacts stored in the index.
+		if desc.Platform == nil || desc.Platform.OS == "unknown" {
+			continue
+		}
+		platforms = append(platforms, *desc.Platform)
+	}
+	return platforms, nil
+}
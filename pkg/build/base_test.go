@@ -0,0 +1,105 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+func TestGetBase_local(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	single := filepath.Join(dir, "single")
+	if _, err := layout.Write(single, empty.Index); err != nil {
+		t.Fatal(err)
+	}
+	l, err := layout.FromPath(single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AppendImage(img); err != nil {
+		t.Fatal(err)
+	}
+
+	multi := filepath.Join(dir, "multi")
+	if _, err := layout.Write(multi, empty.Index); err != nil {
+		t.Fatal(err)
+	}
+	l, err = layout.FromPath(multi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []v1.Image{other, img} {
+		if err := l.AppendImage(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tar := filepath.Join(dir, "image.tar")
+	tag, err := name.NewTag("example.com/base:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tarball.WriteToFile(tar, tag, img); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		path    string
+		wantErr bool
+	}{
+		"layout":                     {path: single},
+		"layout with digest":         {path: multi + "@" + want.String()},
+		"layout without digest":      {path: multi, wantErr: true},
+		"tarball":                    {path: tar},
+		"tarball with digest":        {path: tar + "@" + want.String(), wantErr: true},
+		"layout with unknown digest": {path: single + "@sha256:" + strings.Repeat("0", 64), wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := getBase(te.path)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		d, err := got.Digest()
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if d != want {
+			t.Errorf("[%s] got: %s, want: %s", name, d, want)
+		}
+	}
+}
@@ -9,17 +9,18 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
-	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/pkg/errors"
 	"github.com/toshi0607/jctl/pkg/gobuild"
 	"github.com/toshi0607/jctl/pkg/path"
+	"golang.org/x/sync/errgroup"
 )
 
 const (
@@ -31,45 +32,188 @@ const (
 	modeReadExec         = 0555
 )
 
+// PlatformAll selects every platform the base image supports.
+const PlatformAll = "all"
+
+var defaultPlatform = v1.Platform{OS: "linux", Architecture: "amd64"}
+
 type Builder interface {
-	Build(importpath string) (v1.Image, error)
+	Build(importpath string) (Result, error)
+}
+
+// Result is the output of Build. It is a v1.Image, or a v1.ImageIndex
+// when the application is built for several platforms.
+type Result interface {
+	MediaType() (types.MediaType, error)
+	Digest() (v1.Hash, error)
+	RawManifest() ([]byte, error)
+}
+
+// Options configure a Builder.
+type Options struct {
+	// BaseImage is what Go applications are put on top of. The default
+	// base image is used when it is empty.
+	BaseImage string
+	// Platforms are os/arch[/variant] or PlatformAll. When none is given, a
+	// single image is built for the platform of the base image, linux/amd64
+	// for a multi-platform base. Otherwise Build returns an image index.
+	Platforms []string
+	// GoBuild are passed to go build. Templates in them must be rendered.
+	GoBuild gobuild.Options
+	// NoCache compiles the application even when the build cache has the
+	// output of the same sources and flags.
+	NoCache bool
 }
 
 type builder struct {
 	log          *log.Logger
-	baseImage    v1.Image
+	base         Result
+	platforms    []v1.Platform
+	goBuild      gobuild.Options
+	cache        *cache
 	creationTime v1.Time
 }
 
-func NewBuilder(outStream io.Writer) (Builder, error) {
+// NewBuilder returns a Builder which puts Go applications on top of the
+// base image.
+func NewBuilder(outStream io.Writer, opts Options) (Builder, error) {
 	log := log.New(outStream, "build: ", log.LstdFlags)
-	base, err := getBaseImage()
+	baseImage := opts.BaseImage
+	if baseImage == "" {
+		baseImage = defaultBaseImagePath
+	}
+	base, err := getBase(baseImage)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get base image")
+		return nil, errors.Wrapf(err, "failed to get base image, image: %s", baseImage)
 	}
+	if d, err := base.Digest(); err == nil {
+		log.Printf("base image: %s, digest: %s", baseImage, d)
+	}
+
+	var ps []v1.Platform
+	for _, p := range opts.Platforms {
+		if p == PlatformAll {
+			ps, err = basePlatforms(base)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to list platforms of base image")
+			}
+			break
+		}
+		parsed, err := v1.ParsePlatform(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid platform %q", p)
+		}
+		ps = append(ps, *parsed)
+	}
+	for _, p := range ps {
+		if _, err := platformImage(base, p); err != nil {
+			return nil, err
+		}
+		if _, err := gobuild.VariantEnv(p.Architecture, p.Variant); err != nil {
+			return nil, errors.Wrapf(err, "invalid platform %s", p)
+		}
+	}
+
+	created, err := creationTime()
+	if err != nil {
+		return nil, err
+	}
+
+	var c *cache
+	if !opts.NoCache {
+		if c, err = newCache(); err != nil {
+			log.Printf("build cache disabled, err: %v", err)
+		}
+	}
+
 	return &builder{
 		log:          log,
-		baseImage:    base,
-		creationTime: v1.Time{Time: time.Now()},
+		base:         base,
+		platforms:    ps,
+		goBuild:      opts.GoBuild,
+		cache:        c,
+		creationTime: created,
 	}, nil
 }
 
-func (b *builder) Build(path string) (v1.Image, error) {
-	cf, err := b.baseImage.ConfigFile()
+// creationTime is the creation time of images. It is fixed unless
+// SOURCE_DATE_EPOCH is set, so that the same program always results in the
+// same digest and publishing it again can be skipped.
+func creationTime() (v1.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return v1.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(epoch, 10, 64)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get config file")
+		return v1.Time{}, errors.Wrapf(err, "invalid SOURCE_DATE_EPOCH %q", epoch)
 	}
-	platform := v1.Platform{
-		OS:           cf.OS,
-		Architecture: cf.Architecture,
+	return v1.Time{Time: time.Unix(sec, 0).UTC()}, nil
+}
+
+func (b *builder) Build(path string) (Result, error) {
+	if len(b.platforms) == 0 {
+		base, ok := b.base.(v1.Image)
+		if !ok {
+			var err error
+			base, err = platformImage(b.base, defaultPlatform)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cf, err := base.ConfigFile()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get config file")
+		}
+		return b.buildImage(path, base, v1.Platform{OS: cf.OS, Architecture: cf.Architecture, Variant: cf.Variant})
 	}
 
-	file, err := gobuild.Build(path, platform.OS, platform.Architecture)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to build Go app, path: %s", path)
+	// Each platform is compiled by its own go build, so run them in parallel.
+	imgs := make([]v1.Image, len(b.platforms))
+	var eg errgroup.Group
+	for i, p := range b.platforms {
+		i, p := i, p
+		eg.Go(func() error {
+			base, err := platformImage(b.base, p)
+			if err != nil {
+				return err
+			}
+			b.log.Printf("building for %s", p)
+			img, err := b.buildImage(path, base, p)
+			if err != nil {
+				return errors.Wrapf(err, "failed to build for %s", p)
+			}
+			imgs[i] = img
+			return nil
+		})
 	}
-	defer os.RemoveAll(filepath.Dir(file))
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Keep the manifest format of the base so that the index never mixes
+	// Docker and OCI media types.
+	mt := types.OCIImageIndex
+	if baseMT, err := b.base.MediaType(); err == nil && (baseMT == types.DockerManifestList || baseMT == types.DockerManifestSchema2) {
+		mt = types.DockerManifestList
+	}
+	adds := make([]mutate.IndexAddendum, len(imgs))
+	for i, img := range imgs {
+		p := b.platforms[i]
+		adds[i] = mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &p,
+			},
+		}
+	}
+	return mutate.AppendManifests(mutate.IndexMediaType(empty.Index, mt), adds...), nil
+}
 
+// buildImage puts the application built for platform on top of base.
+// platform is the one the image is advertised as, so that the binary
+// targets its variant too.
+func (b *builder) buildImage(path string, base v1.Image, platform v1.Platform) (v1.Image, error) {
 	var layers []mutate.Addendum
 	dataLayerBuf, err := b.tarJctldata(path)
 	if err != nil {
@@ -91,11 +235,10 @@ func (b *builder) Build(path string) (v1.Image, error) {
 		},
 	})
 	appPath := filepath.Join(appDir, appFileName(path))
-	binaryLayerBuf, err := tarBinary(appPath, file)
+	binaryLayerBytes, err := b.binaryLayer(path, appPath, platform)
 	if err != nil {
 		return nil, err
 	}
-	binaryLayerBytes := binaryLayerBuf.Bytes()
 	binaryLayer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
 		return ioutil.NopCloser(bytes.NewBuffer(binaryLayerBytes)), nil
 	})
@@ -110,7 +253,7 @@ func (b *builder) Build(path string) (v1.Image, error) {
 			Comment:   "go build output, at " + appPath,
 		},
 	})
-	withApp, err := mutate.Append(b.baseImage, layers...)
+	withApp, err := mutate.Append(base, layers...)
 	if err != nil {
 		return nil, err
 	}
@@ -131,6 +274,47 @@ func (b *builder) Build(path string) (v1.Image, error) {
 	return mutate.CreatedAt(image, b.creationTime)
 }
 
+// binaryLayer returns the gzipped layer of the Go application, taken from
+// the cache when the sources, the toolchain and the flags are unchanged.
+// The cache is best effort, so failures of it only fall back to go build.
+func (b *builder) binaryLayer(path, appPath string, platform v1.Platform) ([]byte, error) {
+	// The variant comes before Env so that Env can still override it.
+	opts := b.goBuild
+	variantEnv, err := gobuild.VariantEnv(platform.Architecture, platform.Variant)
+	if err != nil {
+		return nil, err
+	}
+	opts.Env = append(variantEnv, b.goBuild.Env...)
+
+	var key string
+	if b.cache != nil {
+		key, err = gobuild.Key(path, platform.OS, platform.Architecture, opts)
+		if err != nil {
+			b.log.Printf("build cache disabled, path: %s, err: %v", path, err)
+		} else if layer, ok := b.cache.get(key); ok {
+			b.log.Printf("using cached build, path: %s, platform: %s", path, platform)
+			return layer, nil
+		}
+	}
+
+	file, err := gobuild.Build(path, platform.OS, platform.Architecture, opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build Go app, path: %s", path)
+	}
+	defer os.RemoveAll(filepath.Dir(file))
+	buf, err := tarBinary(appPath, file)
+	if err != nil {
+		return nil, err
+	}
+	layer := buf.Bytes()
+	if key != "" {
+		if err := b.cache.put(key, layer); err != nil {
+			b.log.Printf("failed to cache build, path: %s, err: %v", path, err)
+		}
+	}
+	return layer, nil
+}
+
 func tarBinary(name, binary string) (*bytes.Buffer, error) {
 	buf := bytes.NewBuffer(nil)
 	gw, err := gzip.NewWriterLevel(buf, gzip.BestSpeed)
@@ -272,11 +456,3 @@ func (g *builder) jctlDataPath(importpath string) (string, error) {
 	}
 	return filepath.Join(p.Dir, "jctldata"), nil
 }
-
-func getBaseImage() (v1.Image, error) {
-	ref, err := name.ParseReference(defaultBaseImagePath)
-	if err != nil {
-		return nil, err
-	}
-	return remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
-}
@@ -0,0 +1,211 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"debug/buildinfo"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+var (
+	linuxAmd64  = v1.Platform{OS: "linux", Architecture: "amd64"}
+	linuxArmV6  = v1.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}
+	linuxArmV7  = v1.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}
+	attestation = v1.Platform{OS: "unknown", Architecture: "unknown"}
+)
+
+// imageOf returns a random image whose config is of the platform.
+func imageOf(t *testing.T, p v1.Platform) v1.Image {
+	t.Helper()
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf = cf.DeepCopy()
+	cf.OS, cf.Architecture, cf.Variant = p.OS, p.Architecture, p.Variant
+	img, err = mutate.ConfigFile(img, cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// indexOf returns an image index with an image for each platform.
+func indexOf(t *testing.T, platforms ...v1.Platform) v1.ImageIndex {
+	t.Helper()
+	adds := make([]mutate.IndexAddendum, len(platforms))
+	for i := range platforms {
+		p := platforms[i]
+		adds[i] = mutate.IndexAddendum{Add: imageOf(t, p), Descriptor: v1.Descriptor{Platform: &p}}
+	}
+	return mutate.AppendManifests(empty.Index, adds...)
+}
+
+func TestBasePlatforms(t *testing.T) {
+	tests := map[string]struct {
+		base Result
+		want []v1.Platform
+	}{
+		"index": {
+			base: indexOf(t, linuxAmd64, linuxArmV6, attestation),
+			want: []v1.Platform{linuxAmd64, linuxArmV6},
+		},
+		"image": {
+			base: imageOf(t, linuxArmV7),
+			want: []v1.Platform{linuxArmV7},
+		},
+	}
+	for name, te := range tests {
+		got, err := basePlatforms(te.base)
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %v, want: %v", name, got, te.want)
+		}
+	}
+}
+
+func TestPlatformImage(t *testing.T) {
+	index := indexOf(t, linuxAmd64, linuxArmV6)
+	tests := map[string]struct {
+		base     Result
+		platform v1.Platform
+		wantErr  bool
+	}{
+		"index":                 {base: index, platform: linuxArmV6},
+		"index without variant": {base: index, platform: linuxArmV7, wantErr: true},
+		"index without arch":    {base: index, platform: v1.Platform{OS: "linux", Architecture: "arm64"}, wantErr: true},
+		"image":                 {base: imageOf(t, linuxAmd64), platform: linuxAmd64},
+		"image of other arch":   {base: imageOf(t, linuxAmd64), platform: linuxArmV6, wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := platformImage(te.base, te.platform)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		cf, err := got.ConfigFile()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := cf.Platform(); p == nil || !p.Equals(te.platform) {
+			t.Errorf("[%s] got: %v, want: %v", name, p, te.platform)
+		}
+	}
+}
+
+func TestBuild_platforms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "base")
+	if _, err := layout.Write(base, empty.Index); err != nil {
+		t.Fatal(err)
+	}
+	l, err := layout.FromPath(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.AppendIndex(indexOf(t, linuxAmd64, linuxArmV6, attestation)); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := NewBuilder(ioutil.Discard, Options{BaseImage: base, Platforms: []string{PlatformAll}, NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := b.Build("github.com/toshi0607/jctl/testdata/cmd/hello_world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, ok := res.(v1.ImageIndex)
+	if !ok {
+		t.Fatalf("got: %T, want: v1.ImageIndex", res)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Manifests) != 2 {
+		t.Fatalf("got %d manifests, want: 2", len(m.Manifests))
+	}
+
+	wantGOARM := map[string]string{"amd64": "", "arm": "6"}
+	for _, desc := range m.Manifests {
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var goarm string
+		for _, s := range binaryBuildInfo(t, img).Settings {
+			if s.Key == "GOARM" {
+				goarm = s.Value
+			}
+		}
+		if want := wantGOARM[desc.Platform.Architecture]; goarm != want {
+			t.Errorf("[%s] GOARM got: %q, want: %q", desc.Platform, goarm, want)
+		}
+	}
+}
+
+// binaryBuildInfo reads the build info of the application in the top
+// layer of img.
+func binaryBuildInfo(t *testing.T, img v1.Image) *buildinfo.BuildInfo {
+	t.Helper()
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := layers[len(layers)-1].Uncompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			t.Fatal("no binary in the top layer")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := buildinfo.Read(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return info
+	}
+}
@@ -0,0 +1,85 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cacheTTL is how long a layer is kept without being used.
+const cacheTTL = 30 * 24 * time.Hour
+
+// cache keeps the binary layers of previous builds, keyed by gobuild.Key,
+// so that unchanged programs are not compiled again.
+type cache struct {
+	dir string
+}
+
+// newCache returns the cache under the user cache directory, like
+// ~/.cache/jctl/layers on Linux.
+func newCache() (*cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &cache{dir: filepath.Join(dir, "jctl", "layers")}, nil
+}
+
+func (c *cache) path(key string) string {
+	return filepath.Join(c.dir, key+".tar.gz")
+}
+
+// get returns the layer of key, or false when there is none.
+func (c *cache) get(key string) ([]byte, bool) {
+	p := c.path(key)
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+	// The modification time tells prune when the layer was last used.
+	now := time.Now()
+	os.Chtimes(p, now, now)
+	return b, true
+}
+
+// put stores the layer of key. The layer is renamed into place so that a
+// concurrent build never reads a partial one.
+func (c *cache) put(key string, layer []byte) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create cache directory")
+	}
+	f, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return errors.Wrap(err, "failed to create cache file")
+	}
+	_, err = f.Write(layer)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return errors.Wrap(err, "failed to write cache file")
+	}
+	c.prune()
+	return nil
+}
+
+// prune removes layers not used for cacheTTL, along with temporary files
+// left by interrupted builds.
+func (c *cache) prune() {
+	entries, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if time.Since(e.ModTime()) > cacheTTL {
+			os.Remove(filepath.Join(c.dir, e.Name()))
+		}
+	}
+}
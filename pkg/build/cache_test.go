@@ -0,0 +1,43 @@
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &cache{dir: filepath.Join(dir, "layers")}
+
+	if _, ok := c.get("a"); ok {
+		t.Fatal("empty cache should miss")
+	}
+	if err := c.put("a", []byte("layer")); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.get("a")
+	if !ok || string(got) != "layer" {
+		t.Errorf("got: %q, %v, want: %q, true", got, ok, "layer")
+	}
+
+	old := time.Now().Add(-cacheTTL - time.Hour)
+	if err := os.Chtimes(c.path("a"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.put("b", []byte("layer")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.get("a"); ok {
+		t.Error("unused layer should be pruned")
+	}
+	if _, ok := c.get("b"); !ok {
+		t.Error("new layer should be kept")
+	}
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/pkg/errors"
+	"github.com/toshi0607/jctl/pkg/build"
+)
+
+// localRegistry is the registry of the tag written into tarballs, which
+// docker load uses to name the image.
+const localRegistry = "jctl.local"
+
+type buildCommand struct {
+	cli     *cli
+	Tarball string `long:"tarball" value-name:"FILE" description:"write the image to a tarball loadable by docker load (default: <name>.tar)"`
+	Layout  string `long:"layout" value-name:"DIR" description:"write the image to an OCI image layout, required for multi-platform images"`
+	Args    struct {
+		Path string `required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (b *buildCommand) Execute(_ []string) error {
+	c := b.cli
+	path, s, err := c.resolve(b.Args.Path)
+	if err != nil {
+		return err
+	}
+	if b.Layout == "" && len(s.Platforms) != 0 {
+		return errors.New("multi-platform images can only be written with --layout")
+	}
+	builder, err := c.newBuilder(c.OutStream, s)
+	if err != nil {
+		return err
+	}
+	img, err := c.buildImage(c.OutStream, builder, path)
+	if err != nil {
+		return err
+	}
+
+	if b.Layout != "" {
+		if err := writeLayout(b.Layout, img); err != nil {
+			return err
+		}
+		d, err := img.Digest()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.OutStream, "image written, layout: %s, digest: %s\n", b.Layout, d)
+		return nil
+	}
+	i, ok := img.(v1.Image)
+	if !ok {
+		return errors.New("multi-platform images can only be written with --layout")
+	}
+	appName := strings.ToLower(filepath.Base(path))
+	file := b.Tarball
+	if file == "" {
+		file = appName + ".tar"
+	}
+	tag, err := name.NewTag(localRegistry + "/" + appName + ":latest")
+	if err != nil {
+		return err
+	}
+	if err := tarball.WriteToFile(file, tag, i); err != nil {
+		return errors.Wrapf(err, "failed to write tarball, path: %s", file)
+	}
+	fmt.Fprintf(c.OutStream, "image written, tarball: %s, tag: %s\n", file, tag)
+	return nil
+}
+
+// writeLayout appends img to the OCI image layout at dir, creating the
+// layout when it does not exist.
+func writeLayout(dir string, img build.Result) error {
+	l, err := layout.FromPath(dir)
+	if err != nil {
+		l, err = layout.Write(dir, empty.Index)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to open OCI image layout, path: %s", dir)
+	}
+	switch i := img.(type) {
+	case v1.ImageIndex:
+		err = l.AppendIndex(i)
+	case v1.Image:
+		err = l.AppendImage(i)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to write OCI image layout, path: %s", dir)
+	}
+	return nil
+}
@@ -2,19 +2,40 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/pkg/errors"
-	"github.com/toshi0607/jctl/pkg/build"
 	"github.com/toshi0607/jctl/pkg/kubernetes"
-	"github.com/toshi0607/jctl/pkg/path"
-	"github.com/toshi0607/jctl/pkg/publish"
+	"github.com/toshi0607/jctl/pkg/project"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
 )
 
-const defaultTimeoutSecond = 5 * time.Minute
+const (
+	defaultTimeoutSecond = 5 * time.Minute
+	defaultNamespace     = "default"
+	defaultTTLSec        = 300
+)
+
+// Exit codes of jctl itself. They are taken from the range timeout(1) and
+// shells reserve, so that CI can tell them from exit codes of the program.
+const (
+	// ExitCodeError is the exit code when jctl failed, e.g. on a build
+	// error or an invalid flag.
+	ExitCodeError       = 125
+	exitCodeTimeout     = 124
+	exitCodeInterrupted = 130
+)
 
 type CLI interface {
 	Run() int
@@ -25,6 +46,8 @@ type (
 		OutStream, ErrStream io.Writer
 		Version              string
 		Config               config
+		parser               *flags.Parser
+		file                 *project.File
 	}
 
 	config struct {
@@ -32,11 +55,72 @@ type (
 		Version    bool   `short:"v" long:"version" description:"Show version"`
 		Help       bool   `short:"h" long:"help" description:"Show this help message"`
 		KubeConfig string `long:"kubeconfig" description:"absolute path to K8s credential"`
-		TimeoutSec int    `short:"t" long:"timeoutsec" description:"timeout second"`
-		TTLSec     int32  `long:"ttlsec" description:"TTLSecondsAfterFinished of Job. This is alpha feature since v1.12" default:"300"`
-		Args       struct {
-			Path string
-		} `positional-args:"yes"`
+		ConfigFile string `long:"config" description:"path to the config file (default: jctl.yaml at the module root)"`
+		Output     string `short:"o" long:"output" choice:"yaml" choice:"json" choice:"wide" description:"output format of printed objects, wide is for list only (default: yaml, a table for list)"`
+
+		BuildOpts buildFlags `group:"Build Options"`
+		JobOpts   jobFlags   `group:"Job Options"`
+
+		Run       runCommand      `command:"run" description:"Build, publish and run a Go application as Job (default)"`
+		Build     buildCommand    `command:"build" description:"Build the image and write it to a tarball or an OCI image layout"`
+		Publish   publishCommand  `command:"publish" description:"Build and push the image, then print its reference with digest"`
+		Schedule  scheduleCommand `command:"schedule" description:"Build, publish and schedule a Go application as CronJob"`
+		Trigger   triggerCommand  `command:"trigger" description:"Run a CronJob created by schedule right away"`
+		Attach    attachCommand   `command:"attach" description:"Watch a running Job and stream its logs until it finishes"`
+		Logs      logsCommand     `command:"logs" description:"Print logs of the pods of a Job"`
+		List      listCommand     `command:"list" description:"List Jobs created by jctl"`
+		Delete    deleteCommand   `command:"delete" description:"Delete Jobs and their pods"`
+		ConfigCmd configCommand   `command:"config" description:"Inspect the configuration"`
+	}
+
+	buildFlags struct {
+		BaseImage string   `long:"base-image" value-name:"REF|PATH[@DIGEST]" description:"base image as a remote reference, an OCI image layout directory or a tarball (default: gcr.io/distroless/static:latest)"`
+		Platform  []string `long:"platform" value-name:"OS/ARCH[,...]" description:"build an image index for the platforms, or all platforms of the base image with all"`
+		Ldflags   string   `long:"ldflags" description:"-ldflags of go build, templates like {{.Git.ShortCommit}} are expanded"`
+		Gcflags   string   `long:"gcflags" description:"-gcflags of go build"`
+		Tags      string   `long:"tags" value-name:"TAG[,...]" description:"-tags of go build"`
+		Trimpath  bool     `long:"trimpath" description:"-trimpath of go build"`
+		Mod       string   `long:"mod" choice:"readonly" choice:"vendor" choice:"mod" description:"-mod of go build"`
+		BuildEnv  []string `long:"build-env" value-name:"KEY=VAL" description:"environment variable of go build like GOFLAGS or GOEXPERIMENT, repeatable"`
+		Verbose   bool     `long:"verbose" description:"stream the output of go build"`
+		NoCache   bool     `long:"no-cache" description:"compile the program even if the build cache has it"`
+	}
+
+	jobFlags struct {
+		TimeoutSec       int      `short:"t" long:"timeoutsec" description:"timeout second"`
+		TTLSec           int32    `long:"ttlsec" description:"TTLSecondsAfterFinished of Job. This is alpha feature since v1.12" default:"300"`
+		Env              []string `short:"e" long:"env" value-name:"KEY=VAL" description:"environment variable of the Job container, repeatable"`
+		EnvFromSecret    []string `long:"env-from-secret" value-name:"NAME" description:"expose all keys of the Secret as environment variables, repeatable"`
+		EnvFromConfigMap []string `long:"env-from-configmap" value-name:"NAME" description:"expose all keys of the ConfigMap as environment variables, repeatable"`
+		CPU              string   `long:"cpu" value-name:"QUANTITY" description:"cpu request of the Job container, like 500m"`
+		Memory           string   `long:"memory" value-name:"QUANTITY" description:"memory request of the Job container, like 256Mi"`
+		LimitCPU         string   `long:"limit-cpu" value-name:"QUANTITY" description:"cpu limit of the Job container"`
+		LimitMemory      string   `long:"limit-memory" value-name:"QUANTITY" description:"memory limit of the Job container"`
+		EphemeralStorage string   `long:"ephemeral-storage" value-name:"QUANTITY" description:"ephemeral storage request of the Job container"`
+		Parallelism      int32    `long:"parallelism" value-name:"N" description:"number of pods running at the same time"`
+		Completions      int32    `long:"completions" value-name:"N" description:"number of pods which must succeed"`
+		CompletionMode   string   `long:"completion-mode" choice:"NonIndexed" choice:"Indexed" description:"Indexed gives each pod an index in JOB_COMPLETION_INDEX"`
+		BackoffLimit     int32    `long:"backoff-limit" value-name:"N" description:"number of retries before the Job fails (default of the cluster: 6)"`
+		ActiveDeadline   int64    `long:"active-deadline" value-name:"SECONDS" description:"limit of the run time of the Job on the cluster, retries included"`
+		RestartPolicy    string   `long:"restart-policy" choice:"Never" choice:"OnFailure" description:"restart policy of the pods (default: Never)"`
+		PodFailureRule   []string `long:"pod-failure-rule" value-name:"ACTION:exit-code=CODE[,...]|ACTION:disruption" description:"rule of the pod failure policy like FailJob:exit-code=2 or Ignore:disruption, repeatable"`
+		ImagePullSecret  []string `long:"image-pull-secret" value-name:"NAME" description:"image pull secret of the pods, repeatable, or none (default: image-puller)"`
+		CreatePullSecret bool     `long:"create-pull-secret" description:"create or update the image pull secret from the credentials used to push the image"`
+		ServiceAccount   string   `long:"service-account" value-name:"NAME" description:"service account of the pods"`
+		NodeSelector     []string `long:"node-selector" value-name:"KEY=VAL" description:"node label the pods must run on, repeatable"`
+		Toleration       []string `long:"toleration" value-name:"KEY[=VAL][:EFFECT]" description:"taint of nodes the pods tolerate, repeatable"`
+		Affinity         string   `long:"affinity" value-name:"YAML" description:"affinity of the pods in YAML or JSON, like the one of a pod spec"`
+		PriorityClass    string   `long:"priority-class" value-name:"NAME" description:"priority class of the pods"`
+		RuntimeClass     string   `long:"runtime-class" value-name:"NAME" description:"runtime class of the pods"`
+		Mount            []string `long:"mount" value-name:"KIND:SOURCE:PATH" description:"mount secret:NAME:PATH, configmap:NAME:PATH, pvc:CLAIM:PATH[:ro] or emptydir:PATH[:SIZE], repeatable"`
+		File             []string `long:"file" value-name:"LOCAL=REMOTE" description:"upload the local file to the absolute path in the Job container, repeatable"`
+		Dir              []string `long:"dir" value-name:"LOCAL=REMOTE" description:"upload the local directory to the absolute path in the Job container, repeatable"`
+		Artifacts        string   `long:"artifacts" value-name:"PATH[=LOCAL]" description:"download the directory of the Job container to LOCAL/POD after it exits (default LOCAL: jctl-artifacts)"`
+		UploaderImage    string   `long:"uploader-image" value-name:"REF" description:"image with sh and tar receiving large uploads and handing over artifacts (default: busybox:1.36)"`
+		SecretEnv        []string `long:"secret-env" value-name:"KEY=SECRET:KEY" description:"environment variable read from a key of the Secret, repeatable"`
+		Detach           bool     `short:"d" long:"detach" description:"return right after the Job is created and print its name. Use attach to watch it later"`
+		KeepOnInterrupt  bool     `long:"keep-on-interrupt" description:"leave the Job running when jctl is interrupted or times out"`
+		DryRun           string   `long:"dry-run" optional:"yes" optional-value:"client" choice:"client" choice:"server" description:"print the Job instead of running it. client renders it locally, server validates it on the cluster"`
 	}
 )
 
@@ -49,81 +133,342 @@ func New(outStream, errStream io.Writer, version string) CLI {
 	}
 }
 
-func (c *cli) initConfig() error {
-	p := flags.NewParser(&c.Config, flags.None)
-	_, err := p.Parse()
-	if err != nil {
+func (c *cli) Run() int {
+	err := c.execute(os.Args[1:])
+	if err == nil {
+		return 0
+	}
+	fmt.Fprintln(c.ErrStream, err)
+	var jf *kubernetes.JobFailedError
+	switch {
+	case errors.As(err, &jf):
+		return jf.ExitCode
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return exitCodeInterrupted
+	}
+	return ExitCodeError
+}
+
+func (c *cli) execute(args []string) error {
+	err := c.parse(args)
+	// "jctl [path]" is a shorthand for "jctl run [path]".
+	if c.parser.Active == nil && isCommandError(err) {
+		if c.Config.Help {
+			return c.help()
+		}
+		err = c.parse(append([]string{"run"}, args...))
+	}
+	if _, ok := err.(*flags.Error); ok {
 		return errors.Wrap(err, "failed to parse config")
 	}
+	return err
+}
+
+func (c *cli) parse(args []string) error {
+	c.newParser()
+	_, err := c.parser.ParseArgs(args)
+	return err
+}
 
+func (c *cli) newParser() {
+	c.Config = config{}
+	c.Config.Run.cli = c
+	c.Config.Build.cli = c
+	c.Config.Publish.cli = c
+	c.Config.Schedule.cli = c
+	c.Config.Trigger.cli = c
+	c.Config.Attach.cli = c
+	c.Config.Logs.cli = c
+	c.Config.List.cli = c
+	c.Config.Delete.cli = c
+	c.Config.ConfigCmd.View.cli = c
+
+	c.parser = flags.NewParser(&c.Config, flags.PassDoubleDash)
+	c.parser.CommandHandler = c.handle
+}
+
+func isCommandError(err error) bool {
+	fe, ok := err.(*flags.Error)
+	return ok && (fe.Type == flags.ErrUnknownCommand || fe.Type == flags.ErrCommandRequired)
+}
+
+func (c *cli) handle(cmd flags.Commander, args []string) error {
 	if c.Config.Version {
-		return fmt.Errorf("jctl version %s", c.Version)
+		fmt.Fprintf(c.OutStream, "jctl version %s\n", c.Version)
+		return nil
+	}
+	if c.Config.Help || cmd == nil {
+		return c.help()
+	}
+	// wide is a table format, which only list prints.
+	if _, ok := cmd.(*listCommand); c.Config.Output == "wide" && !ok {
+		return errors.New("output format wide is only supported by list")
 	}
 
-	if c.Config.Help || c.Config.Args.Path == "" {
-		p.WriteHelp(c.ErrStream)
-		return errors.New("")
+	f, err := project.Find(c.Config.ConfigFile)
+	if err != nil {
+		return err
 	}
+	c.file = f
 
-	return nil
+	return cmd.Execute(args)
 }
 
-func (c *cli) Run() int {
-	err := c.initConfig()
+func (c *cli) help() error {
+	c.parser.WriteHelp(c.ErrStream)
+	return errors.New("")
+}
+
+// settings resolves the effective settings of importpath. Explicit flags
+// win over the JCTL_DOCKER_REPO environment variable, which wins over
+// jctl.yaml, which wins over the defaults.
+func (c *cli) settings(importpath string) (project.Settings, error) {
+	ttlSec := int32(defaultTTLSec)
+	s := project.Settings{
+		Namespace: defaultNamespace,
+		TTLSec:    &ttlSec,
+	}
+	s = s.Merge(c.file.For(importpath))
+	s = s.Merge(project.Settings{Repo: os.Getenv("JCTL_DOCKER_REPO")})
+
+	f, err := c.flagSettings()
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, err)
-		return 1
+		return project.Settings{}, err
 	}
+	return s.Merge(f), nil
+}
 
-	builder, err := build.NewBuilder(c.OutStream)
+// flagSettings returns the settings given explicitly on the command line.
+func (c *cli) flagSettings() (project.Settings, error) {
+	var s project.Settings
+	if c.isSet("namespace") {
+		s.Namespace = c.Config.Namespace
+	}
+	if c.isSet("timeoutsec") {
+		s.TimeoutSec = c.Config.JobOpts.TimeoutSec
+	}
+	s.BaseImage = c.Config.BuildOpts.BaseImage
+	for _, p := range c.Config.BuildOpts.Platform {
+		s.Platforms = append(s.Platforms, strings.Split(p, ",")...)
+	}
+	b, err := c.buildFlagSettings()
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, err)
-		return 1
+		return project.Settings{}, err
 	}
-	publisher, err := publish.New(c.OutStream)
+	s.Build = b
+	if c.isSet("ttlsec") {
+		ttlSec := c.Config.JobOpts.TTLSec
+		s.TTLSec = &ttlSec
+	}
+	env, err := toMap(c.Config.JobOpts.Env, "KEY=VAL")
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, err)
-		return 1
+		return project.Settings{}, errors.Wrap(err, "invalid env")
 	}
-	path, err := path.NewBuilder(c.Config.Args.Path).Build()
+	s.Env = env
+	secretEnv, err := toMap(c.Config.JobOpts.SecretEnv, "KEY=secret:key")
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, err)
-		return 1
+		return project.Settings{}, errors.Wrap(err, "invalid secret env")
 	}
-
-	fmt.Fprintln(c.OutStream, "building image...")
-	img, err := builder.Build(path)
+	s.SecretEnv = secretEnv
+	s.EnvFromSecret = c.Config.JobOpts.EnvFromSecret
+	s.EnvFromConfigMap = c.Config.JobOpts.EnvFromConfigMap
+	r, err := c.resourceFlagSettings()
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, errors.Wrapf(err, "failed to build image, path: %s", path))
-		return 1
+		return project.Settings{}, err
+	}
+	s.Resources = r
+	if c.isSet("parallelism") {
+		s.Parallelism = &c.Config.JobOpts.Parallelism
+	}
+	if c.isSet("completions") {
+		s.Completions = &c.Config.JobOpts.Completions
+	}
+	s.CompletionMode = c.Config.JobOpts.CompletionMode
+	if c.isSet("backoff-limit") {
+		s.BackoffLimit = &c.Config.JobOpts.BackoffLimit
+	}
+	if c.isSet("active-deadline") {
+		s.ActiveDeadlineSec = &c.Config.JobOpts.ActiveDeadline
+	}
+	s.RestartPolicy = c.Config.JobOpts.RestartPolicy
+	s.ImagePullSecrets = c.Config.JobOpts.ImagePullSecret
+	if c.isSet("create-pull-secret") {
+		s.CreatePullSecret = &c.Config.JobOpts.CreatePullSecret
+	}
+	s.Mounts = c.Config.JobOpts.Mount
+	s.Files = c.Config.JobOpts.File
+	s.Dirs = c.Config.JobOpts.Dir
+	s.Artifacts = c.Config.JobOpts.Artifacts
+	s.UploaderImage = c.Config.JobOpts.UploaderImage
+	if err := c.schedulingFlagSettings(&s); err != nil {
+		return project.Settings{}, err
+	}
+	if len(c.Config.JobOpts.PodFailureRule) != 0 {
+		var p batchv1.PodFailurePolicy
+		for _, r := range c.Config.JobOpts.PodFailureRule {
+			rule, err := kubernetes.ParsePodFailureRule(r)
+			if err != nil {
+				return project.Settings{}, err
+			}
+			p.Rules = append(p.Rules, rule)
+		}
+		s.PodFailurePolicy = &p
 	}
-	fmt.Fprintln(c.OutStream, "publishing image...")
-	ref, err := publisher.Publish(img, path)
+	return s, nil
+}
+
+func (c *cli) schedulingFlagSettings(s *project.Settings) error {
+	o := c.Config.JobOpts
+	s.ServiceAccountName = o.ServiceAccount
+	s.PriorityClassName = o.PriorityClass
+	s.RuntimeClassName = o.RuntimeClass
+	nodeSelector, err := toMap(o.NodeSelector, "KEY=VAL")
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, errors.Wrapf(err, "failed to publish image, path: %s", path))
-		return 1
+		return errors.Wrap(err, "invalid node selector")
+	}
+	s.NodeSelector = nodeSelector
+	for _, t := range o.Toleration {
+		tol, err := kubernetes.ParseToleration(t)
+		if err != nil {
+			return err
+		}
+		s.Tolerations = append(s.Tolerations, tol)
+	}
+	if o.Affinity != "" {
+		var a corev1.Affinity
+		if err := yaml.UnmarshalStrict([]byte(o.Affinity), &a); err != nil {
+			return errors.Wrap(err, "invalid affinity")
+		}
+		s.Affinity = &a
+	}
+	return nil
+}
+
+func (c *cli) resourceFlagSettings() (*corev1.ResourceRequirements, error) {
+	o := c.Config.JobOpts
+	var r corev1.ResourceRequirements
+	for _, f := range []struct {
+		list  *corev1.ResourceList
+		name  corev1.ResourceName
+		value string
+	}{
+		{&r.Requests, corev1.ResourceCPU, o.CPU},
+		{&r.Requests, corev1.ResourceMemory, o.Memory},
+		{&r.Requests, corev1.ResourceEphemeralStorage, o.EphemeralStorage},
+		{&r.Limits, corev1.ResourceCPU, o.LimitCPU},
+		{&r.Limits, corev1.ResourceMemory, o.LimitMemory},
+	} {
+		if f.value == "" {
+			continue
+		}
+		q, err := kubernetes.ParseQuantity(f.name, f.value)
+		if err != nil {
+			return nil, err
+		}
+		if *f.list == nil {
+			*f.list = corev1.ResourceList{}
+		}
+		(*f.list)[f.name] = q
+	}
+	if r.Requests == nil && r.Limits == nil {
+		return nil, nil
 	}
+	return &r, nil
+}
 
-	k, err := kubernetes.New(c.OutStream, c.Config.Namespace, c.Config.KubeConfig, c.Config.TTLSec)
+func (c *cli) buildFlagSettings() (*project.BuildSettings, error) {
+	var b project.BuildSettings
+	b.Ldflags = c.Config.BuildOpts.Ldflags
+	b.Gcflags = c.Config.BuildOpts.Gcflags
+	if c.Config.BuildOpts.Tags != "" {
+		b.Tags = strings.Split(c.Config.BuildOpts.Tags, ",")
+	}
+	if c.isSet("trimpath") {
+		b.Trimpath = &c.Config.BuildOpts.Trimpath
+	}
+	b.Mod = c.Config.BuildOpts.Mod
+	env, err := toMap(c.Config.BuildOpts.BuildEnv, "KEY=VAL")
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, err)
-		return 1
+		return nil, errors.Wrap(err, "invalid build env")
 	}
+	b.Env = env
+	if b.Ldflags == "" && b.Gcflags == "" && b.Tags == nil && b.Trimpath == nil && b.Mod == "" && b.Env == nil {
+		return nil, nil
+	}
+	return &b, nil
+}
 
-	// The timeout bounds job execution only. Build and publish time varies
-	// with network conditions and must not eat into the job's budget.
-	timeout := defaultTimeoutSecond
-	if c.Config.TimeoutSec != 0 {
-		timeout = time.Duration(c.Config.TimeoutSec) * time.Second
+// isSet reports whether the option was given on the command line. Options
+// of the active command are looked up along with the global ones.
+func (c *cli) isSet(longName string) bool {
+	cmd := c.parser.Command
+	if c.parser.Active != nil {
+		cmd = c.parser.Active
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	o := cmd.FindOptionByLongName(longName)
+	return o != nil && o.IsSet() && !o.IsSetDefault()
+}
+
+func (c *cli) timeout(s project.Settings) time.Duration {
+	if s.TimeoutSec != 0 {
+		return time.Duration(s.TimeoutSec) * time.Second
+	}
+	return defaultTimeoutSecond
+}
 
-	err = k.Create(ctx, ref.Name())
+// interruptible returns a context canceled on SIGINT or SIGTERM. After the
+// first signal, the default handling is restored so that a second one
+// terminates jctl immediately.
+func interruptible(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+func (c *cli) print(obj interface{}) error {
+	var b []byte
+	var err error
+	switch c.Config.Output {
+	case "json":
+		b, err = json.MarshalIndent(obj, "", "    ")
+		b = append(b, '\n')
+	default:
+		b, err = yaml.Marshal(obj)
+	}
 	if err != nil {
-		fmt.Fprintln(c.ErrStream, err)
-		return 1
+		return err
 	}
+	_, err = c.OutStream.Write(b)
+	return err
+}
 
-	return 0
+func toMap(pairs []string, want string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		i := strings.Index(p, "=")
+		if i <= 0 {
+			return nil, errors.Errorf("%q, want %s", p, want)
+		}
+		m[p[:i]] = p[i+1:]
+	}
+	return m, nil
+}
+
+// toPairs is the inverse of toMap. Pairs are sorted by key so that the
+// generated manifest is stable.
+func toPairs(m map[string]string) []string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
 }
@@ -17,7 +17,7 @@ func TestCli_Run(t *testing.T) {
 	}{
 		"with import path": {
 			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/hello_world"},
-			wantOutputs: []string{"job finished", "job created"},
+			wantOutputs: []string{"job finished", "job created", "] hello from jctl"},
 			wantCode:    0,
 		},
 		"with relative path": {
@@ -28,17 +28,52 @@ func TestCli_Run(t *testing.T) {
 		"with timeout option": {
 			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/long_hello_world", "-t", "5"},
 			wantOutputs: []string{"job execution timeout", "job created"},
-			wantCode:    1,
+			wantCode:    124,
+		},
+		"with program args": {
+			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/echo_args", "--", "--dry-run", "--limit", "10"},
+			wantOutputs: []string{"args: --dry-run --limit 10", "args: --dry-run,--limit,10"},
+			wantCode:    0,
+		},
+		"with env": {
+			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/print_env", "--env", "JCTL_GREETING=hello"},
+			wantOutputs: []string{"JCTL_GREETING=hello"},
+			wantCode:    0,
+		},
+		"with invalid env": {
+			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/print_env", "--env", "JCTL_GREETING"},
+			wantOutputs: []string{"invalid env"},
+			wantCode:    125,
+		},
+		"with failing program": {
+			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/exit_code"},
+			wantOutputs: []string{"job failed", "job created"},
+			wantCode:    3,
+		},
+		"with compile error": {
+			args:        []string{"path", "github.com/toshi0607/jctl/testdata/cmd/compile_error"},
+			wantOutputs: []string{"go build failed", "main.go:8:14: undefined: greeting"},
+			wantCode:    125,
+		},
+		"publish command": {
+			args:        []string{"path", "publish", "github.com/toshi0607/jctl/testdata/cmd/hello_world"},
+			wantOutputs: []string{"publishing image...", "@sha256:"},
+			wantCode:    0,
+		},
+		"list command": {
+			args:        []string{"path", "list"},
+			wantOutputs: []string{"NAME", "STATUS"},
+			wantCode:    0,
 		},
 		"with version": {
 			args:        []string{"path", "-v"},
 			wantOutputs: []string{"jctl version"},
-			wantCode:    1,
+			wantCode:    0,
 		},
 		"with invalid flag": {
 			args:        []string{"path", "-foo"},
 			wantOutputs: []string{"failed to parse config"},
-			wantCode:    1,
+			wantCode:    125,
 		},
 	}
 
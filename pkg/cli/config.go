@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"github.com/toshi0607/jctl/pkg/path"
+)
+
+type (
+	configCommand struct {
+		View configViewCommand `command:"view" description:"Print the effective configuration merged from defaults, jctl.yaml and flags"`
+	}
+
+	configViewCommand struct {
+		cli  *cli
+		Args struct {
+			Path string `description:"show the configuration of the program at this path"`
+		} `positional-args:"yes"`
+	}
+)
+
+func (v *configViewCommand) Execute(_ []string) error {
+	var importpath string
+	if v.Args.Path != "" {
+		var err error
+		importpath, err = path.NewBuilder(v.Args.Path).Build()
+		if err != nil {
+			return err
+		}
+	}
+	s, err := v.cli.settings(importpath)
+	if err != nil {
+		return err
+	}
+	return v.cli.print(s)
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+	"github.com/toshi0607/jctl/pkg/build"
+	"github.com/toshi0607/jctl/pkg/gobuild"
+	"github.com/toshi0607/jctl/pkg/path"
+	"github.com/toshi0607/jctl/pkg/project"
+	"github.com/toshi0607/jctl/pkg/publish"
+)
+
+// resolve turns a path given on the command line into an importpath and
+// returns the effective settings of it.
+func (c *cli) resolve(p string) (string, project.Settings, error) {
+	importpath, err := path.NewBuilder(p).Build()
+	if err != nil {
+		return "", project.Settings{}, err
+	}
+	s, err := c.settings(importpath)
+	if err != nil {
+		return "", project.Settings{}, err
+	}
+	return importpath, s, nil
+}
+
+// release builds the image of importpath and publishes it. The publisher
+// is returned for its credentials.
+func (c *cli) release(out io.Writer, importpath string, s project.Settings) (name.Reference, publish.Publisher, error) {
+	builder, err := c.newBuilder(out, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	publisher, err := publish.New(out, s.Repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := c.buildImage(out, builder, importpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	ref, err := c.publishImage(out, publisher, img, importpath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, publisher, nil
+}
+
+func (c *cli) newBuilder(out io.Writer, s project.Settings) (build.Builder, error) {
+	goBuild, err := goBuildOptions(s.Build)
+	if err != nil {
+		return nil, err
+	}
+	if c.Config.BuildOpts.Verbose {
+		goBuild.Output = out
+	}
+	return build.NewBuilder(out, build.Options{
+		BaseImage: s.BaseImage,
+		Platforms: s.Platforms,
+		GoBuild:   goBuild,
+		NoCache:   c.Config.BuildOpts.NoCache,
+	})
+}
+
+func (c *cli) buildImage(out io.Writer, builder build.Builder, importpath string) (build.Result, error) {
+	fmt.Fprintln(out, "building image...")
+	img, err := builder.Build(importpath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to build image, path: %s", importpath)
+	}
+	return img, nil
+}
+
+func (c *cli) publishImage(out io.Writer, publisher publish.Publisher, img build.Result, importpath string) (name.Reference, error) {
+	fmt.Fprintln(out, "publishing image...")
+	ref, err := publisher.Publish(img, importpath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to publish image, path: %s", importpath)
+	}
+	return ref, nil
+}
+
+func goBuildOptions(b *project.BuildSettings) (gobuild.Options, error) {
+	if b == nil {
+		return gobuild.Options{}, nil
+	}
+	opts := gobuild.Options{
+		Ldflags: b.Ldflags,
+		Gcflags: b.Gcflags,
+		Tags:    b.Tags,
+		Mod:     b.Mod,
+		Env:     toPairs(b.Env),
+	}
+	if b.Trimpath != nil {
+		opts.Trimpath = *b.Trimpath
+	}
+	return opts.Render()
+}
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/toshi0607/jctl/pkg/gobuild"
+	"github.com/toshi0607/jctl/pkg/kubernetes"
+	"github.com/toshi0607/jctl/pkg/path"
+	"github.com/toshi0607/jctl/pkg/project"
+	"github.com/toshi0607/jctl/pkg/publish"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+type (
+	attachCommand struct {
+		cli  *cli
+		Args struct {
+			Job string `required:"yes"`
+		} `positional-args:"yes"`
+	}
+
+	logsCommand struct {
+		cli    *cli
+		Follow bool `short:"f" long:"follow" description:"keep streaming until the Job finishes"`
+		Args   struct {
+			Job string `required:"yes"`
+		} `positional-args:"yes"`
+	}
+
+	listCommand struct {
+		cli           *cli
+		AllNamespaces bool `short:"A" long:"all-namespaces" description:"list Jobs in all namespaces"`
+		Args          struct {
+			Path string `description:"list only Jobs running the program at this path"`
+		} `positional-args:"yes"`
+	}
+
+	deleteCommand struct {
+		cli  *cli
+		Args struct {
+			Jobs []string `required:"1"`
+		} `positional-args:"yes"`
+	}
+)
+
+func (a *attachCommand) Execute(_ []string) error {
+	k, err := a.cli.globalJobCli()
+	if err != nil {
+		return err
+	}
+	ctx, stop := interruptible(context.Background())
+	defer stop()
+	return k.Attach(ctx, a.Args.Job)
+}
+
+func (l *logsCommand) Execute(_ []string) error {
+	k, err := l.cli.globalJobCli()
+	if err != nil {
+		return err
+	}
+	ctx, stop := interruptible(context.Background())
+	defer stop()
+	return k.Logs(ctx, l.Args.Job, l.Follow)
+}
+
+func (l *listCommand) Execute(_ []string) error {
+	var importpath string
+	if l.Args.Path != "" {
+		var err error
+		importpath, err = path.NewBuilder(l.Args.Path).Build()
+		if err != nil {
+			return err
+		}
+	}
+	k, err := l.cli.globalJobCli()
+	if err != nil {
+		return err
+	}
+	jobs, err := k.List(context.Background(), kubernetes.ListOptions{
+		AllNamespaces: l.AllNamespaces,
+		ImportPath:    importpath,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch l.cli.Config.Output {
+	case "json", "yaml":
+		return l.cli.print(&batchv1.JobList{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"},
+			Items:    jobs,
+		})
+	default:
+		return l.printTable(jobs, l.cli.Config.Output == "wide")
+	}
+}
+
+func (l *listCommand) printTable(jobs []batchv1.Job, wide bool) error {
+	w := tabwriter.NewWriter(l.cli.OutStream, 0, 8, 2, ' ', 0)
+	header := []string{"NAME", "PROGRAM", "STATUS", "START", "DURATION", "IMAGE"}
+	if l.AllNamespaces {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	if wide {
+		header = append(header, "USER", "COMMIT", "VERSION")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := range jobs {
+		j := &jobs[i]
+		o := kubernetes.OriginOf(j)
+		start, d := "<none>", "<none>"
+		if j.Status.StartTime != nil {
+			start = j.Status.StartTime.Local().Format("2006-01-02 15:04:05")
+			d = duration.HumanDuration(kubernetes.Duration(j))
+		}
+		image := kubernetes.Image(j)
+		if !wide {
+			image = shortDigest(image)
+		}
+		row := []string{j.Name, kubernetes.OrNone(o.ImportPath), kubernetes.Status(j), start, d, image}
+		if l.AllNamespaces {
+			row = append([]string{j.Namespace}, row...)
+		}
+		if wide {
+			row = append(row, kubernetes.OrNone(o.User), kubernetes.OrNone(o.Commit), kubernetes.OrNone(o.Version))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// shortDigest returns the first 12 hex digits of the digest of an image
+// reference like repo@sha256:..., or the reference itself without a digest.
+func shortDigest(image string) string {
+	i := strings.Index(image, "@sha256:")
+	if i < 0 {
+		return image
+	}
+	d := image[i+len("@sha256:"):]
+	if len(d) > 12 {
+		d = d[:12]
+	}
+	return d
+}
+
+func (d *deleteCommand) Execute(_ []string) error {
+	k, err := d.cli.globalJobCli()
+	if err != nil {
+		return err
+	}
+	for _, j := range d.Args.Jobs {
+		if err := k.Delete(context.Background(), j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// origin describes the program and who runs it, recorded on the Job.
+func (c *cli) origin(importpath string) kubernetes.Origin {
+	o := kubernetes.Origin{
+		ImportPath: importpath,
+		Commit:     gobuild.GitCommit(),
+		Version:    c.Version,
+	}
+	if u, err := user.Current(); err == nil {
+		o.User = u.Username
+	}
+	return o
+}
+
+// applyPullSecret creates or updates the image pull secret of the Job from
+// the credentials of the publisher, when the settings ask for it.
+func (c *cli) applyPullSecret(ctx context.Context, k kubernetes.JobCli, p publish.Publisher, s project.Settings, opts kubernetes.JobOptions) error {
+	if s.CreatePullSecret == nil || !*s.CreatePullSecret {
+		return nil
+	}
+	b, err := p.DockerConfigJSON()
+	if err != nil {
+		return errors.Wrap(err, "failed to create image pull secret")
+	}
+	return k.ApplyPullSecret(ctx, kubernetes.DefaultPullSecret(opts.ImagePullSecrets), b)
+}
+
+func (c *cli) jobCli(out io.Writer, s project.Settings) (kubernetes.JobCli, error) {
+	return kubernetes.New(out, s.Namespace, c.Config.KubeConfig, *s.TTLSec)
+}
+
+// globalJobCli returns a JobCli for commands which are not bound to an
+// importpath, in the namespace of the top level settings.
+func (c *cli) globalJobCli() (kubernetes.JobCli, error) {
+	s, err := c.settings("")
+	if err != nil {
+		return nil, err
+	}
+	return c.jobCli(c.OutStream, s)
+}
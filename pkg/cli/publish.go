@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"fmt"
+)
+
+type publishCommand struct {
+	cli  *cli
+	Args struct {
+		Path string `required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (p *publishCommand) Execute(_ []string) error {
+	c := p.cli
+	path, s, err := c.resolve(p.Args.Path)
+	if err != nil {
+		return err
+	}
+
+	// Only the reference goes to stdout so that scripts can capture it.
+	ref, _, err := c.release(c.ErrStream, path, s)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.OutStream, ref.Name())
+	return nil
+}
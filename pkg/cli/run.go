@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/toshi0607/jctl/pkg/kubernetes"
+	"github.com/toshi0607/jctl/pkg/project"
+)
+
+type runCommand struct {
+	cli  *cli
+	Args struct {
+		Path string
+		Args []string `description:"arguments passed to the program, put them after --"`
+	} `positional-args:"yes"`
+}
+
+func (r *runCommand) Execute(_ []string) error {
+	if r.Args.Path == "" {
+		return r.cli.help()
+	}
+	return r.cli.run(r.Args.Path, r.Args.Args)
+}
+
+func (c *cli) run(p string, args []string) error {
+	path, s, err := c.resolve(p)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		s.Args = args
+	}
+
+	// Validate the Job settings before spending time on build and publish.
+	opts, err := jobOptions(s)
+	if err != nil {
+		return err
+	}
+	if opts.Artifacts != nil {
+		// The sidecar outlives the timeout a little so that jctl always
+		// gets to fetch the artifacts before it is gone.
+		opts.Artifacts.WaitSec = int64(c.timeout(s) / time.Second)
+	}
+
+	// Progress goes to stderr on dry-run to keep stdout a valid manifest,
+	// and on detach to leave only the Job name on stdout.
+	out := c.OutStream
+	if c.Config.JobOpts.DryRun != "" || c.Config.JobOpts.Detach {
+		out = c.ErrStream
+	}
+
+	ref, publisher, err := c.release(out, path, s)
+	if err != nil {
+		return err
+	}
+	opts.Image = ref.Name()
+	opts.Origin = c.origin(path)
+	opts.KeepOnInterrupt = c.Config.JobOpts.KeepOnInterrupt
+	opts.Detach = c.Config.JobOpts.Detach
+
+	if c.Config.JobOpts.DryRun == "client" {
+		return c.print(kubernetes.BuildJob(opts, s.Namespace, *s.TTLSec))
+	}
+
+	k, err := c.jobCli(out, s)
+	if err != nil {
+		return err
+	}
+
+	// The timeout bounds job execution only. Build and publish time varies
+	// with network conditions and must not eat into the job's budget.
+	ctx, stop := interruptible(context.Background())
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, c.timeout(s))
+	defer cancel()
+
+	if c.Config.JobOpts.DryRun == "" {
+		if err := c.applyPullSecret(ctx, k, publisher, s, opts); err != nil {
+			return err
+		}
+	}
+
+	if c.Config.JobOpts.DryRun == "server" {
+		job, err := k.DryRun(ctx, opts)
+		if err != nil {
+			return err
+		}
+		return c.print(job)
+	}
+
+	name, err := k.Create(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if opts.Detach {
+		fmt.Fprintln(c.OutStream, name)
+	}
+	return nil
+}
+
+// jobOptions converts and validates the settings of the Job container.
+func jobOptions(s project.Settings) (kubernetes.JobOptions, error) {
+	env, err := kubernetes.ParseEnv(toPairs(s.Env), toPairs(s.SecretEnv))
+	if err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	envFrom, err := kubernetes.ParseEnvFrom(s.EnvFromSecret, s.EnvFromConfigMap)
+	if err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	if err := kubernetes.ValidateParallelism(s.Parallelism, s.Completions, s.CompletionMode); err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	if s.BackoffLimit != nil && *s.BackoffLimit < 0 {
+		return kubernetes.JobOptions{}, errors.Errorf("invalid backoff limit %d, must not be negative", *s.BackoffLimit)
+	}
+	if s.ActiveDeadlineSec != nil && *s.ActiveDeadlineSec <= 0 {
+		return kubernetes.JobOptions{}, errors.Errorf("invalid active deadline %d, must be positive", *s.ActiveDeadlineSec)
+	}
+	if err := kubernetes.ValidateRetryPolicy(s.RestartPolicy, s.PodFailurePolicy); err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	pullSecrets, err := kubernetes.ParseImagePullSecrets(s.ImagePullSecrets)
+	if err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	if s.CreatePullSecret != nil && *s.CreatePullSecret && kubernetes.DefaultPullSecret(pullSecrets) == "" {
+		return kubernetes.JobOptions{}, errors.Errorf("can not create image pull secret %q", kubernetes.PullSecretNone)
+	}
+	scheduling := kubernetes.Scheduling{
+		ServiceAccountName: s.ServiceAccountName,
+		NodeSelector:       s.NodeSelector,
+		Tolerations:        s.Tolerations,
+		Affinity:           s.Affinity,
+		PriorityClassName:  s.PriorityClassName,
+		RuntimeClassName:   s.RuntimeClassName,
+	}
+	if err := scheduling.Validate(); err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	var mounts []kubernetes.Mount
+	for _, m := range s.Mounts {
+		mount, err := kubernetes.ParseMount(m)
+		if err != nil {
+			return kubernetes.JobOptions{}, err
+		}
+		mounts = append(mounts, mount)
+	}
+	var uploads []kubernetes.Upload
+	for _, l := range []struct {
+		uploads []string
+		dir     bool
+	}{{s.Files, false}, {s.Dirs, true}} {
+		for _, u := range l.uploads {
+			up, err := kubernetes.ParseUpload(u, l.dir)
+			if err != nil {
+				return kubernetes.JobOptions{}, err
+			}
+			uploads = append(uploads, up)
+		}
+	}
+	up, err := kubernetes.NewUploads(uploads, s.UploaderImage)
+	if err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	artifacts, err := kubernetes.ParseArtifacts(s.Artifacts, s.UploaderImage)
+	if err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	if artifacts != nil {
+		if err := artifacts.Validate(s.RestartPolicy); err != nil {
+			return kubernetes.JobOptions{}, err
+		}
+	}
+	if err := kubernetes.ValidatePaths(mounts, uploads, artifacts); err != nil {
+		return kubernetes.JobOptions{}, err
+	}
+	opts := kubernetes.JobOptions{
+		Args:                  s.Args,
+		Env:                   env,
+		EnvFrom:               envFrom,
+		Parallelism:           s.Parallelism,
+		Completions:           s.Completions,
+		CompletionMode:        s.CompletionMode,
+		BackoffLimit:          s.BackoffLimit,
+		ActiveDeadlineSeconds: s.ActiveDeadlineSec,
+		RestartPolicy:         s.RestartPolicy,
+		PodFailurePolicy:      s.PodFailurePolicy,
+		ImagePullSecrets:      pullSecrets,
+		Scheduling:            scheduling,
+		Mounts:                mounts,
+		Uploads:               up,
+		Artifacts:             artifacts,
+	}
+	if s.Resources != nil {
+		if err := kubernetes.ValidateResources(*s.Resources); err != nil {
+			return kubernetes.JobOptions{}, err
+		}
+		opts.Resources = *s.Resources
+	}
+	return opts, nil
+}
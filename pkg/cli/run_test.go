@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/toshi0607/jctl/pkg/project"
+)
+
+func TestJobOptions_paths(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.yaml"), []byte("stage: prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		settings project.Settings
+		wantErr  bool
+	}{
+		"distinct": {settings: project.Settings{Dirs: []string{dir + "=/config"}, Mounts: []string{"pvc:datasets:/data"}, Artifacts: "/out"}},
+		"upload and mount": {
+			settings: project.Settings{Dirs: []string{dir + "=/data"}, Mounts: []string{"pvc:datasets:/data"}},
+			wantErr:  true,
+		},
+		"artifacts and mount": {
+			settings: project.Settings{Mounts: []string{"emptydir:/out"}, Artifacts: "/out"},
+			wantErr:  true,
+		},
+	}
+	for name, te := range tests {
+		_, err := jobOptions(te.settings)
+		if te.wantErr && err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+		if !te.wantErr && err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+		}
+	}
+}
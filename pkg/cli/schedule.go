@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/toshi0607/jctl/pkg/kubernetes"
+)
+
+type (
+	scheduleCommand struct {
+		cli               *cli
+		Cron              string `long:"cron" value-name:"SCHEDULE" required:"yes" description:"schedule in the cron format like \"0 3 * * *\""`
+		Name              string `long:"name" description:"name of the CronJob (default: the last element of the importpath)"`
+		TimeZone          string `long:"timezone" value-name:"TZ" description:"time zone of the schedule like Asia/Tokyo (default: the time zone of the cluster)"`
+		Suspend           bool   `long:"suspend" description:"create or update the CronJob suspended"`
+		ConcurrencyPolicy string `long:"concurrency-policy" choice:"Allow" choice:"Forbid" choice:"Replace" description:"what to do when the previous Job is still running"`
+		SuccessfulHistory int32  `long:"successful-history" value-name:"N" description:"number of succeeded Jobs to keep"`
+		FailedHistory     int32  `long:"failed-history" value-name:"N" description:"number of failed Jobs to keep"`
+		Args              struct {
+			Path string   `required:"yes"`
+			Args []string `description:"arguments passed to the program, put them after --"`
+		} `positional-args:"yes"`
+	}
+
+	triggerCommand struct {
+		cli  *cli
+		Args struct {
+			CronJob string `required:"yes"`
+		} `positional-args:"yes"`
+	}
+)
+
+func (sc *scheduleCommand) Execute(_ []string) error {
+	c := sc.cli
+	path, s, err := c.resolve(sc.Args.Path)
+	if err != nil {
+		return err
+	}
+	if len(sc.Args.Args) != 0 {
+		s.Args = sc.Args.Args
+	}
+
+	if len(s.Files) != 0 || len(s.Dirs) != 0 {
+		return errors.New("files and dirs can not be uploaded to CronJobs, mount a volume instead")
+	}
+	if s.Artifacts != "" {
+		return errors.New("artifacts can not be downloaded from CronJobs, mount a volume instead")
+	}
+	cron := sc.cronOptions(path)
+	if err := cron.Validate(); err != nil {
+		return err
+	}
+	opts, err := jobOptions(s)
+	if err != nil {
+		return err
+	}
+
+	// Only the CronJob goes to stdout on dry-run.
+	out := c.OutStream
+	if c.Config.JobOpts.DryRun != "" {
+		out = c.ErrStream
+	}
+	ref, publisher, err := c.release(out, path, s)
+	if err != nil {
+		return err
+	}
+	opts.Image = ref.Name()
+	opts.Origin = c.origin(path)
+
+	if c.Config.JobOpts.DryRun == "client" {
+		return c.print(kubernetes.BuildCronJob(opts, cron, s.Namespace, *s.TTLSec))
+	}
+
+	k, err := c.jobCli(out, s)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	cron.DryRun = c.Config.JobOpts.DryRun == "server"
+	if !cron.DryRun {
+		if err := c.applyPullSecret(ctx, k, publisher, s, opts); err != nil {
+			return err
+		}
+	}
+	cj, err := k.Schedule(ctx, opts, cron)
+	if err != nil {
+		return err
+	}
+	if cron.DryRun {
+		return c.print(cj)
+	}
+	return nil
+}
+
+func (sc *scheduleCommand) cronOptions(importpath string) kubernetes.CronOptions {
+	c := sc.cli
+	o := kubernetes.CronOptions{
+		Name:              sc.Name,
+		Schedule:          sc.Cron,
+		TimeZone:          sc.TimeZone,
+		ConcurrencyPolicy: sc.ConcurrencyPolicy,
+	}
+	if o.Name == "" {
+		o.Name = strings.NewReplacer("_", "-", ".", "-").Replace(strings.ToLower(filepath.Base(importpath)))
+	}
+	if c.isSet("suspend") {
+		o.Suspend = &sc.Suspend
+	}
+	if c.isSet("successful-history") {
+		o.SuccessfulJobsHistoryLimit = &sc.SuccessfulHistory
+	}
+	if c.isSet("failed-history") {
+		o.FailedJobsHistoryLimit = &sc.FailedHistory
+	}
+	return o
+}
+
+func (t *triggerCommand) Execute(_ []string) error {
+	c := t.cli
+	s, err := c.settings("")
+	if err != nil {
+		return err
+	}
+	// Like run, only the Job name goes to stdout on detach.
+	out := c.OutStream
+	if c.Config.JobOpts.Detach {
+		out = c.ErrStream
+	}
+	k, err := c.jobCli(out, s)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptible(context.Background())
+	defer stop()
+	name, err := k.Trigger(ctx, t.Args.CronJob)
+	if err != nil {
+		return err
+	}
+	if c.Config.JobOpts.Detach {
+		fmt.Fprintln(c.OutStream, name)
+		return nil
+	}
+	return k.Attach(ctx, name)
+}
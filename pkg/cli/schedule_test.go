@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/toshi0607/jctl/pkg/kubernetes"
+)
+
+func TestScheduleCommand_cronOptions(t *testing.T) {
+	n := func(i int32) *int32 { return &i }
+	suspend := true
+	tests := map[string]struct {
+		args []string
+		want kubernetes.CronOptions
+	}{
+		"defaults": {
+			args: []string{"schedule", "--cron", "0 3 * * *", "example.com/cmd/nightly_batch"},
+			want: kubernetes.CronOptions{Name: "nightly-batch", Schedule: "0 3 * * *"},
+		},
+		"all options": {
+			args: []string{"schedule", "--cron", "0 3 * * *", "--name", "nightly", "--timezone", "Asia/Tokyo",
+				"--suspend", "--concurrency-policy", "Forbid", "--successful-history", "0", "--failed-history", "5",
+				"example.com/cmd/nightly_batch"},
+			want: kubernetes.CronOptions{
+				Name:                       "nightly",
+				Schedule:                   "0 3 * * *",
+				TimeZone:                   "Asia/Tokyo",
+				Suspend:                    &suspend,
+				ConcurrencyPolicy:          "Forbid",
+				SuccessfulJobsHistoryLimit: n(0),
+				FailedJobsHistoryLimit:     n(5),
+			},
+		},
+	}
+	for name, te := range tests {
+		var buf bytes.Buffer
+		c := New(&buf, &buf, "test").(*cli)
+		c.newParser()
+		var got kubernetes.CronOptions
+		c.parser.CommandHandler = func(cmd flags.Commander, _ []string) error {
+			got = c.Config.Schedule.cronOptions(c.Config.Schedule.Args.Path)
+			return nil
+		}
+		if _, err := c.parser.ParseArgs(te.args); err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
@@ -2,39 +2,127 @@ package gobuild
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
-func Build(importpath, goos, goarch string) (string, error) {
+// Options are the flags and environment variables of go build.
+type Options struct {
+	Ldflags  string
+	Gcflags  string
+	Tags     []string
+	Trimpath bool
+	Mod      string
+	// Env is added to the environment of go build, e.g. GOFLAGS=... or
+	// GOEXPERIMENT=...
+	Env []string
+	// Output receives what go build prints while it runs. The output is
+	// also kept in Error when the build fails.
+	Output io.Writer
+}
+
+func Build(importpath, goos, goarch string, opts Options) (string, error) {
 	tmpDir, err := ioutil.TempDir("", "jctl")
 	if err != nil {
 		return "", err
 	}
 	file := filepath.Join(tmpDir, "out")
 
-	args := make([]string, 0, 3)
-	args = append(args, "build")
-	args = append(args, "-o", file)
-	args = append(args, importpath)
-	cmd := exec.Command("go", args...)
+	cmd := exec.Command("go", opts.args(file, importpath)...)
+	cmd.Env = opts.env(goos, goarch)
+
+	var output bytes.Buffer
+	var w io.Writer = &output
+	if opts.Output != nil {
+		w = io.MultiWriter(&output, opts.Output)
+	}
+	cmd.Stderr = w
+	cmd.Stdout = w
 
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", &Error{
+			ImportPath: importpath,
+			GOOS:       goos,
+			GOARCH:     goarch,
+			Output:     output.String(),
+			Err:        err,
+		}
+	}
+	return file, nil
+}
+
+// env is the environment of go build. Later values win, so the target
+// platform cannot be overridden by the environment of the user while Env
+// still can.
+func (o Options) env(goos, goarch string) []string {
+	return append(os.Environ(), o.buildEnv(goos, goarch)...)
+}
+
+// buildEnv is the part of env set by jctl rather than inherited from the
+// environment of the user.
+func (o Options) buildEnv(goos, goarch string) []string {
 	defaultEnv := []string{
 		"CGO_ENABLED=0",
 		"GOOS=" + goos,
 		"GOARCH=" + goarch,
 	}
-	cmd.Env = append(defaultEnv, os.Environ()...)
+	return append(defaultEnv, o.Env...)
+}
 
-	var output bytes.Buffer
-	cmd.Stderr = &output
-	cmd.Stdout = &output
+var (
+	armVariant   = regexp.MustCompile(`^v([5-7])$`)
+	arm64Variant = regexp.MustCompile(`^v(8|9)(\.[0-9])?$`)
+	amd64Variant = regexp.MustCompile(`^v[1-4]$`)
+)
 
-	if err := cmd.Run(); err != nil {
-		os.RemoveAll(tmpDir)
-		return "", err
+// VariantEnv returns the environment of go build which targets the variant
+// of the architecture, like GOARM=6 for arm/v6. Without it, the binary
+// would use the instructions of the default variant of Go.
+func VariantEnv(goarch, variant string) ([]string, error) {
+	if variant == "" {
+		return nil, nil
 	}
-	return file, nil
+	switch {
+	case goarch == "arm" && armVariant.MatchString(variant):
+		return []string{"GOARM=" + variant[1:]}, nil
+	case goarch == "arm64" && arm64Variant.MatchString(variant):
+		if !strings.Contains(variant, ".") {
+			variant += ".0"
+		}
+		return []string{"GOARM64=" + variant}, nil
+	case goarch == "amd64" && amd64Variant.MatchString(variant):
+		return []string{"GOAMD64=" + variant}, nil
+	}
+	return nil, errors.Errorf("unsupported variant %s of %s", variant, goarch)
+}
+
+func (o Options) args(file, importpath string) []string {
+	args := make([]string, 0, 12)
+	args = append(args, "build")
+	if o.Ldflags != "" {
+		args = append(args, "-ldflags", o.Ldflags)
+	}
+	if o.Gcflags != "" {
+		args = append(args, "-gcflags", o.Gcflags)
+	}
+	if len(o.Tags) != 0 {
+		args = append(args, "-tags", strings.Join(o.Tags, ","))
+	}
+	if o.Trimpath {
+		args = append(args, "-trimpath")
+	}
+	if o.Mod != "" {
+		args = append(args, "-mod", o.Mod)
+	}
+	args = append(args, "-o", file)
+	args = append(args, importpath)
+	return args
 }
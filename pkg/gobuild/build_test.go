@@ -0,0 +1,63 @@
+package gobuild
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestBuild_compileError(t *testing.T) {
+	_, err := Build("github.com/toshi0607/jctl/testdata/cmd/compile_error", "linux", "amd64", Options{})
+	var be *Error
+	if !errors.As(err, &be) {
+		t.Fatalf("got: %v, want: *Error", err)
+	}
+	if !strings.Contains(be.Error(), "undefined: greeting") {
+		t.Errorf("got: %s, want compiler output in message", be.Error())
+	}
+	ds := be.Diagnostics()
+	if len(ds) != 1 {
+		t.Fatalf("got: %v, want 1 diagnostic", ds)
+	}
+	d := ds[0]
+	if !strings.HasSuffix(d.File, "compile_error/main.go") || d.Line != 8 || d.Column != 14 || d.Message != "undefined: greeting" {
+		t.Errorf("got: %+v", d)
+	}
+}
+
+func TestVariantEnv(t *testing.T) {
+	tests := map[string]struct {
+		goarch  string
+		variant string
+		want    []string
+		wantErr bool
+	}{
+		"no variant":    {goarch: "arm"},
+		"arm v6":        {goarch: "arm", variant: "v6", want: []string{"GOARM=6"}},
+		"arm v7":        {goarch: "arm", variant: "v7", want: []string{"GOARM=7"}},
+		"arm64 v8":      {goarch: "arm64", variant: "v8", want: []string{"GOARM64=v8.0"}},
+		"arm64 v8.2":    {goarch: "arm64", variant: "v8.2", want: []string{"GOARM64=v8.2"}},
+		"amd64 v3":      {goarch: "amd64", variant: "v3", want: []string{"GOAMD64=v3"}},
+		"unknown arm":   {goarch: "arm", variant: "v8", wantErr: true},
+		"unknown amd64": {goarch: "amd64", variant: "v5", wantErr: true},
+		"no variants":   {goarch: "s390x", variant: "v1", wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := VariantEnv(te.goarch, te.variant)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %v, want: %v", name, got, te.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package gobuild
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Error is returned by Build when go build fails. Output holds what go
+	// build printed, typically compiler diagnostics.
+	Error struct {
+		ImportPath string
+		GOOS       string
+		GOARCH     string
+		Output     string
+		Err        error
+	}
+
+	// Diagnostic is a position and message reported by the compiler.
+	// Column is 0 when go build reported none.
+	Diagnostic struct {
+		File    string
+		Line    int
+		Column  int
+		Message string
+	}
+)
+
+var diagnosticPattern = regexp.MustCompile(`^(\S[^:]*\.go):(\d+)(?::(\d+))?: (.+)$`)
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("go build failed, importpath: %s, platform: %s/%s: %v", e.ImportPath, e.GOOS, e.GOARCH, e.Err)
+	if out := strings.TrimSpace(e.Output); out != "" {
+		msg += "\n" + out
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostics parses the file:line:col: message lines of Output so that
+// editors and wrappers can jump to the positions.
+func (e *Error) Diagnostics() []Diagnostic {
+	var ds []Diagnostic
+	for _, l := range strings.Split(e.Output, "\n") {
+		m := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(l))
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		ds = append(ds, Diagnostic{
+			File:    m[1],
+			Line:    line,
+			Column:  col,
+			Message: m[4],
+		})
+	}
+	return ds
+}
+
+func (d Diagnostic) String() string {
+	if d.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
@@ -0,0 +1,126 @@
+package gobuild
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// toolchainEnv are the variables of go env which change the output of go
+// build for the same sources. go env as a whole is not usable since some
+// values, like GOGCCFLAGS, differ on every call.
+var toolchainEnv = []string{
+	"GOVERSION", "GOROOT", "GOFLAGS", "GOEXPERIMENT", "CGO_ENABLED",
+	"GOAMD64", "GOARM", "GOARM64", "GO386", "GOMIPS", "GOMIPS64", "GOPPC64", "GORISCV64", "GOWASM",
+}
+
+// listedPackage is the part of go list -json the key is made of.
+type listedPackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	HFiles     []string
+	SFiles     []string
+	SysoFiles  []string
+	EmbedFiles []string
+}
+
+// Key digests what go build reads to build importpath for the platform:
+// the toolchain, the flags, the go.mod of the main module and the sources
+// of every package it depends on. The same key means the same binary.
+// Packages of the standard library are covered by the version of Go
+// instead of their sources. The environment of the user counts only
+// through go env, so that unrelated variables leave the key unchanged.
+func Key(importpath, goos, goarch string, opts Options) (string, error) {
+	h := sha256.New()
+	env := opts.env(goos, goarch)
+	fmt.Fprintf(h, "args: %q\n", opts.args("", importpath))
+	fmt.Fprintf(h, "env: %q\n", opts.buildEnv(goos, goarch))
+
+	goEnv, err := goOutput(env, append([]string{"env"}, append(toolchainEnv, "GOMOD")...)...)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get go env")
+	}
+	h.Write(goEnv)
+	// The go directive of go.mod changes the language version and the
+	// GODEBUG defaults the program is built with.
+	lines := strings.Split(strings.TrimSuffix(string(goEnv), "\n"), "\n")
+	if goMod := lines[len(lines)-1]; goMod != "" && goMod != os.DevNull {
+		if err := hashFile(h, goMod); err != nil {
+			return "", err
+		}
+	}
+
+	listArgs := []string{"list", "-deps", "-json"}
+	if len(opts.Tags) != 0 {
+		listArgs = append(listArgs, "-tags", strings.Join(opts.Tags, ","))
+	}
+	if opts.Mod != "" {
+		listArgs = append(listArgs, "-mod", opts.Mod)
+	}
+	list, err := goOutput(env, append(listArgs, importpath)...)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list dependencies, path: %s", importpath)
+	}
+	dec := json.NewDecoder(bytes.NewReader(list))
+	for dec.More() {
+		var p listedPackage
+		if err := dec.Decode(&p); err != nil {
+			return "", errors.Wrap(err, "failed to decode go list")
+		}
+		fmt.Fprintf(h, "package: %s %s\n", p.ImportPath, p.Dir)
+		if p.Standard {
+			continue
+		}
+		for _, files := range [][]string{p.GoFiles, p.CgoFiles, p.CFiles, p.CXXFiles, p.HFiles, p.SFiles, p.SysoFiles, p.EmbedFiles} {
+			for _, f := range files {
+				if err := hashFile(h, filepath.Join(p.Dir, f)); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "file: %s %x\n", name, h.Sum(nil))
+	return nil
+}
+
+func goOutput(env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrap(err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package gobuild
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	const path = "github.com/toshi0607/jctl/testdata/cmd/hello_world"
+	key := func(goos, goarch string, opts Options) string {
+		k, err := Key(path, goos, goarch, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+
+	base := key("linux", "amd64", Options{})
+	if again := key("linux", "amd64", Options{}); again != base {
+		t.Errorf("key changed without changes, got: %s, want: %s", again, base)
+	}
+	for name, k := range map[string]string{
+		"platform": key("linux", "arm64", Options{}),
+		"ldflags":  key("linux", "amd64", Options{Ldflags: "-s -w"}),
+		"tags":     key("linux", "amd64", Options{Tags: []string{"netgo"}}),
+		"env":      key("linux", "amd64", Options{Env: []string{"GOAMD64=v3"}}),
+	} {
+		if k == base {
+			t.Errorf("[%s] key did not change", name)
+		}
+	}
+}
+
+func TestKey_variant(t *testing.T) {
+	const path = "github.com/toshi0607/jctl/testdata/cmd/hello_world"
+	keys := make(map[string]bool)
+	for _, variant := range []string{"v6", "v7"} {
+		env, err := VariantEnv("arm", variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k, err := Key(path, "linux", "arm", Options{Env: env})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[k] = true
+	}
+	if len(keys) != 2 {
+		t.Error("arm/v6 and arm/v7 share the key")
+	}
+}
+
+func TestKey_environment(t *testing.T) {
+	const path = "github.com/toshi0607/jctl/testdata/cmd/hello_world"
+	base, err := Key(path, "linux", "amd64", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JCTL_UNRELATED", "ci-run-42")
+	got, err := Key(path, "linux", "amd64", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != base {
+		t.Errorf("unrelated variable changed the key, got: %s, want: %s", got, base)
+	}
+}
+
+func TestKey_goMod(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("go.mod", "module example.com/batch\n\ngo 1.21\n")
+	t.Chdir(dir)
+	// The toolchain of the test must not be switched by the go directive.
+	t.Setenv("GOTOOLCHAIN", "local")
+
+	base, err := Key("example.com/batch", "linux", "amd64", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("go.mod", "module example.com/batch\n\ngo 1.22\n")
+	got, err := Key("example.com/batch", "linux", "amd64", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == base {
+		t.Error("key did not change with the go directive")
+	}
+}
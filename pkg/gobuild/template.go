@@ -0,0 +1,103 @@
+package gobuild
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// TemplateData is available in templates of Options, for example
+	// -X main.version={{.Git.ShortCommit}}.
+	TemplateData struct {
+		Env  map[string]string
+		Date string
+		Git  GitInfo
+	}
+
+	// GitInfo describes the commit the working directory is on. Fields are
+	// empty outside of a git repository.
+	GitInfo struct {
+		Commit      string
+		ShortCommit string
+		Tag         string
+		Branch      string
+		Dirty       bool
+	}
+)
+
+// Render expands the templates in ldflags, gcflags and env values.
+func (o Options) Render() (Options, error) {
+	if !strings.Contains(o.Ldflags+o.Gcflags+strings.Join(o.Env, ""), "{{") {
+		return o, nil
+	}
+
+	data := TemplateData{
+		Env:  make(map[string]string),
+		Date: time.Now().UTC().Format(time.RFC3339),
+		Git:  Git(),
+	}
+	for _, e := range os.Environ() {
+		if i := strings.Index(e, "="); i > 0 {
+			data.Env[e[:i]] = e[i+1:]
+		}
+	}
+
+	var err error
+	if o.Ldflags, err = render("ldflags", o.Ldflags, data); err != nil {
+		return Options{}, err
+	}
+	if o.Gcflags, err = render("gcflags", o.Gcflags, data); err != nil {
+		return Options{}, err
+	}
+	env := make([]string, len(o.Env))
+	for i, e := range o.Env {
+		if env[i], err = render("env", e, data); err != nil {
+			return Options{}, err
+		}
+	}
+	o.Env = env
+	return o, nil
+}
+
+func render(name, text string, data TemplateData) (string, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid template in %s", name)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", errors.Wrapf(err, "failed to render %s", name)
+	}
+	return buf.String(), nil
+}
+
+// Git describes the commit of the working directory.
+func Git() GitInfo {
+	return GitInfo{
+		Commit:      git("rev-parse", "HEAD"),
+		ShortCommit: git("rev-parse", "--short", "HEAD"),
+		Tag:         git("describe", "--tags", "--abbrev=0"),
+		Branch:      git("rev-parse", "--abbrev-ref", "HEAD"),
+		Dirty:       git("status", "--porcelain") != "",
+	}
+}
+
+// GitCommit returns the commit of the working directory, or "" outside of
+// a git repository. Unlike Git, it runs a single git command.
+func GitCommit() string {
+	return git("rev-parse", "HEAD")
+}
+
+func git(args ...string) string {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,97 @@
+package gobuild
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOptions_Render(t *testing.T) {
+	t.Setenv("JCTL_TEST_VERSION", "v1.2.3")
+	commit := GitCommit()
+	tests := map[string]struct {
+		opts    Options
+		want    Options
+		wantErr bool
+	}{
+		"no templates": {
+			opts: Options{Ldflags: "-s -w", Env: []string{"GOFLAGS=-mod=mod"}},
+			want: Options{Ldflags: "-s -w", Env: []string{"GOFLAGS=-mod=mod"}},
+		},
+		"env": {
+			opts: Options{Ldflags: "-X main.version={{.Env.JCTL_TEST_VERSION}}", Tags: []string{"netgo"}},
+			want: Options{Ldflags: "-X main.version=v1.2.3", Tags: []string{"netgo"}, Env: []string{}},
+		},
+		"git": {
+			opts: Options{Gcflags: "all=-N", Env: []string{"COMMIT={{.Git.Commit}}"}},
+			want: Options{Gcflags: "all=-N", Env: []string{"COMMIT=" + commit}},
+		},
+		"missing env":     {opts: Options{Ldflags: "-X main.version={{.Env.JCTL_TEST_MISSING}}"}, wantErr: true},
+		"unknown field":   {opts: Options{Gcflags: "{{.Commit}}"}, wantErr: true},
+		"invalid in env":  {opts: Options{Env: []string{"A={{.Env"}}, wantErr: true},
+		"invalid ldflags": {opts: Options{Ldflags: "{{if}}"}, wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := te.opts.Render()
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %+v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := TemplateData{
+		Env:  map[string]string{"STAGE": "prod"},
+		Date: "2019-10-04T10:38:57Z",
+		Git:  GitInfo{Commit: "0123456789abcdef", ShortCommit: "0123456", Tag: "v1.0.0", Branch: "main", Dirty: true},
+	}
+	tests := map[string]struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		"plain":         {text: "-s -w", want: "-s -w"},
+		"fields":        {text: "{{.Git.Tag}}-{{.Git.ShortCommit}}@{{.Date}}", want: "v1.0.0-0123456@2019-10-04T10:38:57Z"},
+		"conditional":   {text: "{{.Git.Branch}}{{if .Git.Dirty}}-dirty{{end}}", want: "main-dirty"},
+		"env":           {text: "{{.Env.STAGE}}", want: "prod"},
+		"missing key":   {text: "{{.Env.REGION}}", wantErr: true},
+		"missing field": {text: "{{.Git.Author}}", wantErr: true},
+		"parse error":   {text: "{{.Git.Tag", wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := render("ldflags", te.text, data)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %q", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if got != te.want {
+			t.Errorf("[%s] got: %q, want: %q", name, got, te.want)
+		}
+	}
+}
+
+func TestOptions_Render_date(t *testing.T) {
+	got, err := Options{Ldflags: "{{.Date}}"}.Render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse(time.RFC3339, got.Ldflags); err != nil {
+		t.Errorf("date is not RFC 3339: %v", err)
+	}
+}
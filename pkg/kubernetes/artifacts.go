@@ -0,0 +1,205 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultArtifactsDir is the local directory artifacts are written to.
+	DefaultArtifactsDir = "jctl-artifacts"
+
+	artifactsName     = "jctl-artifacts"
+	artifactsRoot     = "/jctl-artifacts"
+	artifactsDoneFile = "/tmp/jctl-done"
+	// artifactsGraceSec is how much longer than the timeout of jctl the
+	// sidecar waits, so that a pod abandoned by jctl finishes eventually.
+	artifactsGraceSec = 300
+)
+
+// Artifacts is the directory of the Job container downloaded after the
+// container exits. A sidecar keeps the pod alive until jctl has fetched it.
+type Artifacts struct {
+	// Path is the absolute path of the directory in the container.
+	Path string
+	// Local is the local directory the artifacts of each pod are written
+	// to, in a subdirectory named after the pod.
+	Local string
+	// WaitSec is how long the sidecar waits for jctl, on top of a grace
+	// period, before it lets the pod finish without handing over.
+	WaitSec int64
+	image   string
+}
+
+// ParseArtifacts parses the artifacts directory written as PATH[=LOCAL].
+func ParseArtifacts(a, image string) (*Artifacts, error) {
+	if a == "" {
+		return nil, nil
+	}
+	art := &Artifacts{Path: a, Local: DefaultArtifactsDir, image: image}
+	if i := strings.Index(a, "="); i >= 0 {
+		art.Path, art.Local = a[:i], a[i+1:]
+	}
+	if !path.IsAbs(art.Path) {
+		return nil, errors.Errorf("invalid artifacts %q, path must be absolute", a)
+	}
+	if art.Local == "" {
+		return nil, errors.Errorf("invalid artifacts %q, want PATH[=LOCAL]", a)
+	}
+	art.Path = path.Clean(art.Path)
+	if art.image == "" {
+		art.image = DefaultUploaderImage
+	}
+	return art, nil
+}
+
+// jobArtifacts reads the artifacts of a Job created with them from its
+// sidecar and volume, or returns nil. They are written to
+// DefaultArtifactsDir since the Job doesn't record the local directory.
+func jobArtifacts(job *batchv1.Job) *Artifacts {
+	spec := job.Spec.Template.Spec
+	var sidecar bool
+	for _, c := range spec.Containers {
+		if c.Name == artifactsName {
+			sidecar = true
+		}
+	}
+	if !sidecar {
+		return nil
+	}
+	for _, c := range spec.Containers {
+		if c.Name != jobName {
+			continue
+		}
+		for _, m := range c.VolumeMounts {
+			if m.Name == artifactsName {
+				return &Artifacts{Path: m.MountPath, Local: DefaultArtifactsDir}
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that the Job container exits for good, so that the
+// artifacts are complete.
+func (a *Artifacts) Validate(restartPolicy string) error {
+	if restartPolicy == string(corev1.RestartPolicyOnFailure) {
+		return errors.Errorf("artifacts need restart policy %s", corev1.RestartPolicyNever)
+	}
+	return nil
+}
+
+func (a *Artifacts) apply(spec *corev1.PodSpec, c *corev1.Container) {
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         artifactsName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: artifactsName, MountPath: a.Path})
+	spec.Containers = append(spec.Containers, corev1.Container{
+		Name:  artifactsName,
+		Image: a.image,
+		Command: []string{"sh", "-c", fmt.Sprintf(
+			`i=0; until [ -f %[1]s ]; do i=$((i+1)); if [ $i -gt %[2]d ]; then echo "artifacts not fetched by jctl" >&2; exit 0; fi; sleep 1; done`,
+			artifactsDoneFile, a.WaitSec+artifactsGraceSec)},
+		VolumeMounts: []corev1.VolumeMount{{Name: artifactsName, MountPath: artifactsRoot, ReadOnly: true}},
+	})
+}
+
+// fetchArtifacts downloads the artifacts of the pod, then lets the sidecar
+// exit so that the pod finishes. The sidecar is released even when the
+// download fails, since the pod would be stuck otherwise.
+func (c *jobCli) fetchArtifacts(ctx context.Context, pod string, a *Artifacts) error {
+	dir := filepath.Join(a.Local, pod)
+	r, w := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		err := extractTar(r, dir)
+		// Drain the rest so that the exec never blocks on a full pipe.
+		io.Copy(io.Discard, r)
+		errc <- err
+	}()
+	err := c.exec(ctx, pod, artifactsName, []string{"tar", "-cf", "-", "-C", artifactsRoot, "."}, nil, w)
+	w.CloseWithError(err)
+	if extractErr := <-errc; err == nil {
+		err = extractErr
+	}
+
+	if doneErr := c.exec(ctx, pod, artifactsName, []string{"touch", artifactsDoneFile}, nil, nil); err == nil {
+		err = doneErr
+	}
+	if err != nil {
+		return err
+	}
+	c.log.Printf("artifacts downloaded, pod: %s, dir: %s\n", pod, dir)
+	return nil
+}
+
+// extractTar writes the regular files and directories of the tar stream
+// under dir. Entries escaping dir are rejected.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to read artifacts")
+		}
+		name := path.Clean(h.Name)
+		if name == "." {
+			continue
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return errors.Errorf("invalid artifact path %s", h.Name)
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(h.Mode).Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isArtifactsReady reports whether the Job container of the pod exited
+// while the sidecar still holds the artifacts.
+func isArtifactsReady(p *corev1.Pod) bool {
+	var exited, holding bool
+	for _, s := range p.Status.ContainerStatuses {
+		switch s.Name {
+		case jobName:
+			exited = s.State.Terminated != nil
+		case artifactsName:
+			holding = s.State.Running != nil
+		}
+	}
+	return exited && holding
+}
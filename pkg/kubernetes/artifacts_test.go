@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseArtifacts(t *testing.T) {
+	tests := map[string]struct {
+		artifacts string
+		wantPath  string
+		wantLocal string
+		wantErr   bool
+	}{
+		"default local":  {artifacts: "/out/", wantPath: "/out", wantLocal: DefaultArtifactsDir},
+		"local":          {artifacts: "/out=reports", wantPath: "/out", wantLocal: "reports"},
+		"relative path":  {artifacts: "out=reports", wantErr: true},
+		"empty local":    {artifacts: "/out=", wantErr: true},
+		"no path at all": {artifacts: "=reports", wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := ParseArtifacts(te.artifacts, "")
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %+v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if got.Path != te.wantPath || got.Local != te.wantLocal {
+			t.Errorf("[%s] got: %s=%s, want: %s=%s", name, got.Path, got.Local, te.wantPath, te.wantLocal)
+		}
+	}
+}
+
+func TestJobArtifacts(t *testing.T) {
+	tests := map[string]struct {
+		artifacts *Artifacts
+		mounts    []Mount
+		wantPath  string
+	}{
+		"artifacts":    {artifacts: &Artifacts{Path: "/out", Local: "reports"}, wantPath: "/out"},
+		"no artifacts": {mounts: []Mount{{MountPath: "/out", Volume: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}}},
+	}
+	for name, te := range tests {
+		job := BuildJob(JobOptions{Image: "example.com/batch", Artifacts: te.artifacts, Mounts: te.mounts}, "default", 60)
+		got := jobArtifacts(job)
+		if te.wantPath == "" {
+			if got != nil {
+				t.Errorf("[%s] got: %+v, want: nil", name, got)
+			}
+			continue
+		}
+		if got == nil || got.Path != te.wantPath || got.Local != DefaultArtifactsDir {
+			t.Errorf("[%s] got: %+v, want: %s=%s", name, got, te.wantPath, DefaultArtifactsDir)
+		}
+	}
+}
+
+func TestExtractTar(t *testing.T) {
+	tarOf := func(files map[string]string) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for name, content := range files {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Size: int64(len(content)), Typeflag: tar.TypeReg, Mode: 0644}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return &buf
+	}
+
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := extractTar(tarOf(map[string]string{"./report/summary.txt": "ok"}), dir); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "report", "summary.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "ok" {
+		t.Errorf("got: %q, want: %q", b, "ok")
+	}
+
+	for _, name := range []string{"../escaped", "/etc/escaped", "report/../../escaped"} {
+		if err := extractTar(tarOf(map[string]string{name: "x"}), dir); err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// maxCronJobNameLength leaves room for the suffix the CronJob controller
+// appends to the names of the Jobs it creates.
+const maxCronJobNameLength = 52
+
+// CronOptions describe the schedule of a CronJob. Nil values are left to
+// the defaults of the cluster, or to the current values on update.
+type CronOptions struct {
+	Name                       string
+	Schedule                   string
+	TimeZone                   string
+	Suspend                    *bool
+	ConcurrencyPolicy          string
+	SuccessfulJobsHistoryLimit *int32
+	FailedJobsHistoryLimit     *int32
+	// DryRun submits the CronJob in dry-run mode.
+	DryRun bool
+}
+
+// Validate checks the CronOptions before anything is built.
+func (o CronOptions) Validate() error {
+	if errs := validation.IsDNS1123Subdomain(o.Name); len(errs) != 0 {
+		return errors.Errorf("invalid cronjob name %q: %s", o.Name, strings.Join(errs, ", "))
+	}
+	if len(o.Name) > maxCronJobNameLength {
+		return errors.Errorf("invalid cronjob name %q, must be no more than %d characters", o.Name, maxCronJobNameLength)
+	}
+	if !strings.HasPrefix(o.Schedule, "@") && len(strings.Fields(o.Schedule)) != 5 {
+		return errors.Errorf("invalid schedule %q, want 5 fields like \"0 3 * * *\" or a macro like @daily", o.Schedule)
+	}
+	if o.TimeZone != "" {
+		if _, err := time.LoadLocation(o.TimeZone); err != nil {
+			return errors.Wrapf(err, "invalid time zone %q", o.TimeZone)
+		}
+	}
+	switch batchv1.ConcurrencyPolicy(o.ConcurrencyPolicy) {
+	case "", batchv1.AllowConcurrent, batchv1.ForbidConcurrent, batchv1.ReplaceConcurrent:
+	default:
+		return errors.Errorf("invalid concurrency policy %q, want %s, %s or %s", o.ConcurrencyPolicy, batchv1.AllowConcurrent, batchv1.ForbidConcurrent, batchv1.ReplaceConcurrent)
+	}
+	for _, l := range []*int32{o.SuccessfulJobsHistoryLimit, o.FailedJobsHistoryLimit} {
+		if l != nil && *l < 0 {
+			return errors.Errorf("invalid history limit %d, must not be negative", *l)
+		}
+	}
+	return nil
+}
+
+// Schedule creates the CronJob running the Job described by opts, or
+// updates it when it exists.
+func (c *jobCli) Schedule(ctx context.Context, opts JobOptions, cron CronOptions) (*batchv1.CronJob, error) {
+	cj := BuildCronJob(opts, cron, c.Namespace, c.TTLSeconds)
+	var dryRun []string
+	if cron.DryRun {
+		dryRun = []string{metav1.DryRunAll}
+	}
+	client := c.Clientset.BatchV1().CronJobs(c.Namespace)
+
+	current, err := client.Get(ctx, cron.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		created, err := client.Create(ctx, cj, metav1.CreateOptions{DryRun: dryRun})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create cronjob, namespace: %s, name: %s", c.Namespace, cron.Name)
+		}
+		c.log.Printf("cronjob created, name: %s, schedule: %s\n", created.Name, created.Spec.Schedule)
+		created.TypeMeta = cj.TypeMeta
+		return created, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get cronjob, namespace: %s, name: %s", c.Namespace, cron.Name)
+	}
+
+	if cron.Suspend == nil {
+		cj.Spec.Suspend = current.Spec.Suspend
+	}
+	current.Labels = cj.Labels
+	current.Annotations = cj.Annotations
+	current.Spec = cj.Spec
+	updated, err := client.Update(ctx, current, metav1.UpdateOptions{DryRun: dryRun})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update cronjob, namespace: %s, name: %s", c.Namespace, cron.Name)
+	}
+	c.log.Printf("cronjob updated, name: %s, schedule: %s\n", updated.Name, updated.Spec.Schedule)
+	updated.TypeMeta = cj.TypeMeta
+	return updated, nil
+}
+
+// Trigger creates a Job from the template of the CronJob right away, like
+// kubectl create job --from=cronjob/NAME. It returns the name of the Job.
+func (c *jobCli) Trigger(ctx context.Context, cronJob string) (string, error) {
+	cj, err := c.Clientset.BatchV1().CronJobs(c.Namespace).Get(ctx, cronJob, metav1.GetOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get cronjob, namespace: %s, name: %s", c.Namespace, cronJob)
+	}
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for k, v := range cj.Spec.JobTemplate.Annotations {
+		annotations[k] = v
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: cj.Name + "-manual-",
+			Namespace:    c.Namespace,
+			Labels:       cj.Spec.JobTemplate.Labels,
+			Annotations:  annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cj, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}
+	created, err := c.Clientset.BatchV1().Jobs(c.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create job from cronjob, namespace: %s, name: %s", c.Namespace, cronJob)
+	}
+	c.log.Printf("job created,  name: %s, cronjob: %s\n", created.Name, cronJob)
+	return created.Name, nil
+}
+
+// BuildCronJob returns the CronJob which Schedule submits. Its Jobs are
+// the same as the one BuildJob returns.
+func BuildCronJob(opts JobOptions, cron CronOptions, namespace string, ttlSec int32) *batchv1.CronJob {
+	job := BuildJob(opts, namespace, ttlSec)
+	cj := &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cron.Name,
+			Namespace:   namespace,
+			Labels:      job.Labels,
+			Annotations: job.Annotations,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: cron.Schedule,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      job.Labels,
+					Annotations: job.Annotations,
+				},
+				Spec: job.Spec,
+			},
+			Suspend:                    cron.Suspend,
+			SuccessfulJobsHistoryLimit: cron.SuccessfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     cron.FailedJobsHistoryLimit,
+		},
+	}
+	if cron.TimeZone != "" {
+		cj.Spec.TimeZone = &cron.TimeZone
+	}
+	if cron.ConcurrencyPolicy != "" {
+		cj.Spec.ConcurrencyPolicy = batchv1.ConcurrencyPolicy(cron.ConcurrencyPolicy)
+	}
+	return cj
+}
@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ParseEnv converts KEY=VAL pairs and KEY=secret:key references into
+// environment variables of the Job container.
+func ParseEnv(env, secretEnv []string) ([]corev1.EnvVar, error) {
+	vars := make([]corev1.EnvVar, 0, len(env)+len(secretEnv))
+	for _, e := range env {
+		k, v, err := splitEnv(e)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, corev1.EnvVar{Name: k, Value: v})
+	}
+	for _, e := range secretEnv {
+		k, v, err := splitEnv(e)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index(v, ":")
+		if i <= 0 || i == len(v)-1 {
+			return nil, errors.Errorf("invalid secret env %q, want KEY=secret:key", e)
+		}
+		secret, key := v[:i], v[i+1:]
+		if errs := validation.IsDNS1123Subdomain(secret); len(errs) != 0 {
+			return nil, errors.Errorf("invalid secret name %q: %s", secret, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsConfigMapKey(key); len(errs) != 0 {
+			return nil, errors.Errorf("invalid secret key %q: %s", key, strings.Join(errs, ", "))
+		}
+		vars = append(vars, corev1.EnvVar{
+			Name: k,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+					Key:                  key,
+				},
+			},
+		})
+	}
+	return vars, nil
+}
+
+// ParseEnvFrom converts Secret and ConfigMap names into sources whose
+// keys are all exposed as environment variables of the Job container.
+func ParseEnvFrom(secrets, configMaps []string) ([]corev1.EnvFromSource, error) {
+	sources := make([]corev1.EnvFromSource, 0, len(secrets)+len(configMaps))
+	for _, s := range secrets {
+		if errs := validation.IsDNS1123Subdomain(s); len(errs) != 0 {
+			return nil, errors.Errorf("invalid secret name %q: %s", s, strings.Join(errs, ", "))
+		}
+		sources = append(sources, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s},
+			},
+		})
+	}
+	for _, cm := range configMaps {
+		if errs := validation.IsDNS1123Subdomain(cm); len(errs) != 0 {
+			return nil, errors.Errorf("invalid configmap name %q: %s", cm, strings.Join(errs, ", "))
+		}
+		sources = append(sources, corev1.EnvFromSource{
+			ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: cm},
+			},
+		})
+	}
+	return sources, nil
+}
+
+func splitEnv(e string) (string, string, error) {
+	i := strings.Index(e, "=")
+	if i <= 0 {
+		return "", "", errors.Errorf("invalid env %q, want KEY=VAL", e)
+	}
+	k := e[:i]
+	if errs := validation.IsEnvVarName(k); len(errs) != 0 {
+		return "", "", errors.Errorf("invalid env name %q: %s", k, strings.Join(errs, ", "))
+	}
+	return k, e[i+1:], nil
+}
@@ -0,0 +1,48 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// exec runs cmd in the container of the pod like kubectl exec, connecting
+// stdin and stdout when they are given.
+func (c *jobCli) exec(ctx context.Context, pod, container string, cmd []string, stdin io.Reader, stdout io.Writer) error {
+	req := c.Clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(c.Namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    stdout != nil,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	e, err := remotecommand.NewSPDYExecutor(c.config, "POST", req.URL())
+	if err != nil {
+		return errors.Wrap(err, "failed to create executor")
+	}
+
+	var stderr bytes.Buffer
+	err = e.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.Wrapf(err, "failed to exec %s in pod %s: %s", strings.Join(cmd, " "), pod, msg)
+		}
+		return errors.Wrapf(err, "failed to exec %s in pod %s", strings.Join(cmd, " "), pod)
+	}
+	return nil
+}
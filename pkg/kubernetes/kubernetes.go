@@ -2,33 +2,113 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 const (
 	imagePullSecretName = "image-puller"
 	jobName             = "jctl-job"
+	cleanupTimeout      = 30 * time.Second
+	// watchRetryInterval is how long wait waits before watching again
+	// when that failed.
+	watchRetryInterval = 5 * time.Second
+
+	// ExitCodeJobFailed is the exit code reported when the Job failed but
+	// no container exit code is available, e.g. the pod was evicted or the
+	// Job exceeded its deadline. Like the exit codes of jctl itself, it is
+	// out of the range programs usually exit with.
+	ExitCodeJobFailed = 126
+	// ExitCodeOOMKilled is the exit code reported when the container was
+	// killed because it ran out of memory.
+	ExitCodeOOMKilled = 137
 )
 
 type JobCli interface {
-	Create(ctx context.Context, image string) error
+	Create(ctx context.Context, opts JobOptions) (string, error)
+	Attach(ctx context.Context, name string) error
+	DryRun(ctx context.Context, opts JobOptions) (*batchv1.Job, error)
+	Logs(ctx context.Context, name string, follow bool) error
+	List(ctx context.Context, opts ListOptions) ([]batchv1.Job, error)
+	Delete(ctx context.Context, name string) error
+	Schedule(ctx context.Context, opts JobOptions, cron CronOptions) (*batchv1.CronJob, error)
+	Trigger(ctx context.Context, cronJob string) (string, error)
+	ApplyPullSecret(ctx context.Context, name string, dockerConfigJSON []byte) error
+}
+
+// JobOptions describes the Job to be created.
+type JobOptions struct {
+	Image     string
+	Args      []string
+	Env       []corev1.EnvVar
+	EnvFrom   []corev1.EnvFromSource
+	Resources corev1.ResourceRequirements
+	// Parallelism, Completions and CompletionMode are those of JobSpec.
+	// Nil and empty values are left to the defaults of the cluster.
+	Parallelism    *int32
+	Completions    *int32
+	CompletionMode string
+	// BackoffLimit, ActiveDeadlineSeconds and PodFailurePolicy are those of
+	// JobSpec. RestartPolicy of the pod is Never when empty.
+	BackoffLimit          *int32
+	ActiveDeadlineSeconds *int64
+	RestartPolicy         string
+	PodFailurePolicy      *batchv1.PodFailurePolicy
+	// ImagePullSecrets are "image-puller" when nil. Leave it empty to
+	// pull images without any.
+	ImagePullSecrets []corev1.LocalObjectReference
+	Scheduling       Scheduling
+	Mounts           []Mount
+	// Uploads are shipped to the Job at run time. Large ones need Create
+	// to keep running until they are streamed, so Detach is not allowed.
+	Uploads *Uploads
+	// Artifacts are downloaded from each pod after the Job container exits.
+	// Create has to keep running to fetch them, so Detach is not allowed.
+	Artifacts *Artifacts
+	Origin    Origin
+	// KeepOnInterrupt leaves the Job running when ctx of Create is done
+	// before the Job finishes. By default the Job and its pods are deleted.
+	KeepOnInterrupt bool
+	// Detach makes Create return right after the Job is created instead of
+	// waiting for it to finish.
+	Detach bool
+}
+
+// JobFailedError is returned by Create and Attach when the Job finished with
+// the Failed condition.
+type JobFailedError struct {
+	Name     string
+	Reason   string
+	ExitCode int
+}
+
+func (e *JobFailedError) Error() string {
+	return fmt.Sprintf("job failed, name: %s, reason: %s, exit code: %d", e.Name, e.Reason, e.ExitCode)
 }
 
 type (
 	jobCli struct {
 		log       *log.Logger
+		logs      *logStreamer
 		Clientset *kubernetes.Clientset
+		config    *rest.Config
 		Namespace string
 		// TTLSecondsAfterFinished specified in Job
 		TTLSeconds int32
@@ -53,9 +133,11 @@ func New(outStream io.Writer, ns, kc string, ttlSec int32) (JobCli, error) {
 
 	return &jobCli{
 		log:        log,
+		logs:       &logStreamer{out: outStream},
 		Namespace:  ns,
 		TTLSeconds: ttlSec,
 		Clientset:  clientset,
+		config:     config,
 	}, nil
 }
 
@@ -72,73 +154,542 @@ func getKubeConfig(kc string) (string, error) {
 	return "", errors.New("kubectx not found")
 }
 
-func (c *jobCli) Create(ctx context.Context, image string) error {
-	job := buildJob(image, c.Namespace, c.TTLSeconds)
+// Create creates the Job and waits for it to finish unless opts.Detach is
+// set. It returns the name of the Job.
+func (c *jobCli) Create(ctx context.Context, opts JobOptions) (string, error) {
+	if opts.Uploads.NeedsAttach() && opts.Detach {
+		return "", errors.Errorf("uploads larger than %d bytes are streamed by jctl and can not be detached", InlineUploadLimit)
+	}
+	if opts.Artifacts != nil && opts.Detach {
+		return "", errors.New("artifacts are downloaded by jctl and can not be detached")
+	}
+	// The Secret comes first so that the pods find it as soon as they are
+	// scheduled, then it is handed over to the Job.
+	inline := opts.Uploads != nil && !opts.Uploads.NeedsAttach()
+	if inline {
+		if err := c.createUploadSecret(ctx, opts.Uploads); err != nil {
+			return "", err
+		}
+	}
+	job := BuildJob(opts, c.Namespace, c.TTLSeconds)
 	createdJob, err := c.Clientset.BatchV1().Jobs(c.Namespace).Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
-		return errors.Wrapf(err, "failed to create batch, namespace: %s, image: %s", c.Namespace, image)
+		if inline {
+			c.deleteUploadSecret(opts.Uploads)
+		}
+		return "", errors.Wrapf(err, "failed to create batch, namespace: %s, image: %s", c.Namespace, opts.Image)
+	}
+	if inline {
+		// Without the Secret, the pods would wait for its volume forever.
+		if err := c.ownUploadSecret(ctx, opts.Uploads, createdJob); err != nil {
+			c.cleanup(createdJob.Name)
+			return createdJob.Name, err
+		}
+	}
+	if len(opts.Args) == 0 {
+		c.log.Printf("job created,  name: %s", createdJob.Name)
+	} else {
+		c.log.Printf("job created,  name: %s, args: %s", createdJob.Name, quoteArgs(opts.Args))
 	}
-	c.log.Printf("job created,  name: %s", createdJob.Name)
 	if createdJob.Spec.TTLSecondsAfterFinished == nil {
 		c.log.Println("TTLSecondsAfterFinished is not enabled on your cluster")
 	}
+	if opts.Detach {
+		return createdJob.Name, nil
+	}
+
+	err = c.wait(ctx, createdJob.Name, opts.Uploads, opts.Artifacts)
+	// Whatever wait returned, the Job is abandoned once ctx is done.
+	if err != nil && ctx.Err() != nil && !opts.KeepOnInterrupt {
+		c.cleanup(createdJob.Name)
+	}
+	return createdJob.Name, err
+}
 
-	w, err := c.Clientset.BatchV1().Jobs(c.Namespace).Watch(ctx, metav1.ListOptions{})
+// Attach resumes watching a Job created before, streaming the logs of its
+// pods and downloading their artifacts until it finishes. Unlike Create, the Job is left running when ctx
+// is done.
+func (c *jobCli) Attach(ctx context.Context, name string) error {
+	job, err := c.Clientset.BatchV1().Jobs(c.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get job, namespace: %s, name: %s", c.Namespace, name)
+	}
+	c.log.Printf("attached, name: %s\n", name)
+	// The sidecars of a Job left by an interrupted run still wait for
+	// their artifacts to be fetched.
+	artifacts := jobArtifacts(job)
+	if artifacts != nil {
+		c.log.Printf("artifacts are downloaded to %s, path: %s\n", artifacts.Local, artifacts.Path)
+	}
+	return c.wait(ctx, name, nil, artifacts)
+}
+
+// cleanup deletes the Job abandoned by an interrupt, a timeout or a failed
+// setup. ctx may be done at this point, so deletion gets a context of its
+// own.
+func (c *jobCli) cleanup(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	// Foreground deletion removes the pods before the Job, so nothing keeps
+	// running once the Job is gone.
+	if err := c.delete(ctx, name, metav1.DeletePropagationForeground); err != nil {
+		c.log.Printf("failed to clean up job, name: %s, err: %v\n", name, err)
+	}
+}
+
+// wait streams logs of the pods of the Job until it finishes, streams
+// uploads into the pods which wait for them and downloads artifacts from
+// the pods whose Job container exited. It returns JobFailedError
+// when the Job failed.
+func (c *jobCli) wait(ctx context.Context, name string, uploads *Uploads, artifacts *Artifacts) error {
+	jobOpts := metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String(),
+	}
+	w, err := c.Clientset.BatchV1().Jobs(c.Namespace).Watch(ctx, jobOpts)
 	if err != nil {
 		return errors.Wrapf(err, "failed to watch jobs, namespace: %s", c.Namespace)
 	}
-	defer w.Stop()
+	defer func() { w.Stop() }()
+	pw, err := c.Clientset.CoreV1().Pods(c.Namespace).Watch(ctx, podListOptions(name))
+	if err != nil {
+		return errors.Wrapf(err, "failed to watch pods, namespace: %s", c.Namespace)
+	}
+	defer func() { pw.Stop() }()
+
+	// Log streams are bound to ctx and end by themselves once the container
+	// terminates, so waiting for them never outlives the timeout.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// A stream ends when the container terminates. With restart policy
+	// OnFailure, the container restarts in the same pod, so each restart is
+	// streamed again.
+	streamed := make(map[string]bool)
+	follow := func(pod *corev1.Pod) {
+		restarts := restartCount(pod)
+		key := fmt.Sprintf("%s/%d", pod.Name, restarts)
+		if streamed[key] || !isStarted(pod) {
+			return
+		}
+		pn := pod.Name
+		streamed[key] = true
+		if restarts > 0 {
+			c.log.Printf("container restarted, pod: %s, restarts: %d\n", pn, restarts)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.streamLogs(ctx, pn, true); err != nil && ctx.Err() == nil {
+				c.log.Printf("failed to stream logs, pod: %s, err: %v\n", pn, err)
+			}
+		}()
+	}
+
+	uploaded := make(map[string]bool)
+	upload := func(pod *corev1.Pod) {
+		if !uploads.NeedsAttach() || uploaded[pod.Name] || !isUploaderRunning(pod) {
+			return
+		}
+		pn := pod.Name
+		uploaded[pn] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.upload(ctx, pn, uploads); err != nil && ctx.Err() == nil {
+				c.log.Printf("failed to upload files, pod: %s, err: %v\n", pn, err)
+			}
+		}()
+	}
+
+	// The pod does not finish until its artifacts are fetched, so the Job
+	// never finishes with a download pending.
+	fetched := make(map[string]bool)
+	fetch := func(pod *corev1.Pod) {
+		if artifacts == nil || fetched[pod.Name] || !isArtifactsReady(pod) {
+			return
+		}
+		pn := pod.Name
+		fetched[pn] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.fetchArtifacts(ctx, pn, artifacts); err != nil && ctx.Err() == nil {
+				c.log.Printf("failed to download artifacts, pod: %s, err: %v\n", pn, err)
+			}
+		}()
+	}
+
+	// The API server closes watches after its request timeout, so on long
+	// Jobs both watches are started again from a fresh list, retrying while
+	// the API server can not be reached. Uploads and artifacts keep pods
+	// waiting for jctl, so no pod may be missed in between.
+	var podRetry, jobRetry <-chan time.Time
+	pch := pw.ResultChan()
+	rewatchPods := func() {
+		pch, podRetry = nil, time.After(watchRetryInterval)
+		pods, err := c.Clientset.CoreV1().Pods(c.Namespace).List(ctx, podListOptions(name))
+		if err != nil {
+			return
+		}
+		for i := range pods.Items {
+			upload(&pods.Items[i])
+			follow(&pods.Items[i])
+			fetch(&pods.Items[i])
+		}
+		opts := podListOptions(name)
+		opts.ResourceVersion = pods.ResourceVersion
+		npw, err := c.Clientset.CoreV1().Pods(c.Namespace).Watch(ctx, opts)
+		if err != nil {
+			return
+		}
+		pw = npw
+		pch, podRetry = pw.ResultChan(), nil
+	}
+
+	// finished reports the progress of the Job and whether it finished,
+	// with JobFailedError when it failed.
+	var lastProgress string
+	finished := func(job *batchv1.Job) (bool, error) {
+		if p := progress(job); isParallel(job) && p != lastProgress {
+			c.log.Printf("job progress, name: %s, %s\n", name, p)
+			lastProgress = p
+		}
+		cond := finishedCondition(job)
+		if cond == nil {
+			return false, nil
+		}
+		// The pod watch may lag behind the job watch. Pick up pods
+		// which finished before we saw them so no output is lost.
+		pods, err := c.Clientset.CoreV1().Pods(c.Namespace).List(ctx, podListOptions(name))
+		if err == nil {
+			for i := range pods.Items {
+				follow(&pods.Items[i])
+				fetch(&pods.Items[i])
+			}
+		}
+		wg.Wait()
+		if cond.Type == batchv1.JobFailed {
+			jf := &JobFailedError{
+				Name:     name,
+				Reason:   cond.Reason,
+				ExitCode: ExitCodeJobFailed,
+			}
+			if err == nil {
+				jf.ExitCode = exitCode(pods.Items)
+			}
+			c.log.Printf("job failed, name: %s, reason: %s\n", name, cond.Reason)
+			return true, jf
+		}
+		c.log.Printf("job finished, name: %s\n", name)
+		return true, nil
+	}
+
 	ch := w.ResultChan()
+	rewatchJob := func() (bool, error) {
+		ch, jobRetry = nil, time.After(watchRetryInterval)
+		jobs, err := c.Clientset.BatchV1().Jobs(c.Namespace).List(ctx, jobOpts)
+		if err != nil {
+			return false, nil
+		}
+		if len(jobs.Items) == 0 {
+			return true, errors.Errorf("job deleted before it finished, name: %s", name)
+		}
+		if done, err := finished(&jobs.Items[0]); done {
+			return true, err
+		}
+		opts := jobOpts
+		opts.ResourceVersion = jobs.ResourceVersion
+		nw, err := c.Clientset.BatchV1().Jobs(c.Namespace).Watch(ctx, opts)
+		if err != nil {
+			return false, nil
+		}
+		w = nw
+		ch, jobRetry = w.ResultChan(), nil
+		return false, nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
-			c.log.Printf("job execution timeout name: %s\n", createdJob.Name)
-			return errors.Wrap(ctx.Err(), "job execution timeout")
+			return c.abandoned(ctx, name)
+		case <-podRetry:
+			rewatchPods()
+		case obj, ok := <-pch:
+			if !ok {
+				pw.Stop()
+				rewatchPods()
+				continue
+			}
+			if pod, ok := obj.Object.(*corev1.Pod); ok {
+				upload(pod)
+				follow(pod)
+				fetch(pod)
+			}
+		case <-jobRetry:
+			if done, err := rewatchJob(); done {
+				return err
+			}
 		case obj, ok := <-ch:
 			if !ok {
-				return errors.Errorf("watch channel closed before job finished, name: %s", createdJob.Name)
+				// Cancelling ctx closes the watch too, possibly before
+				// ctx.Done is selected.
+				if ctx.Err() != nil {
+					return c.abandoned(ctx, name)
+				}
+				w.Stop()
+				if done, err := rewatchJob(); done {
+					return err
+				}
+				continue
 			}
 			job, ok := obj.Object.(*batchv1.Job)
-			if !ok {
-				c.log.Printf("unexpected kind object: %v", obj)
+			if !ok || job.Name != name {
 				continue
 			}
-			if createdJob.Name == job.Name && isFinished(job) {
-				c.log.Printf("job finished, name: %s\n", createdJob.Name)
-				return nil
+			if done, err := finished(job); done {
+				return err
 			}
 		}
 	}
 }
 
-func buildJob(image, namespace string, ttlSec int32) *batchv1.Job {
-	return &batchv1.Job{
+// abandoned reports why wait stopped before the Job finished. The error
+// wraps ctx.Err() so that callers can tell an interrupt from a timeout.
+func (c *jobCli) abandoned(ctx context.Context, name string) error {
+	if ctx.Err() == context.Canceled {
+		c.log.Printf("job interrupted, name: %s\n", name)
+		return errors.Wrap(ctx.Err(), "job interrupted")
+	}
+	c.log.Printf("job execution timeout name: %s\n", name)
+	return errors.Wrap(ctx.Err(), "job execution timeout")
+}
+
+// DryRun submits the Job in dry-run mode so that admission webhooks and
+// quotas validate it without running anything.
+func (c *jobCli) DryRun(ctx context.Context, opts JobOptions) (*batchv1.Job, error) {
+	job := BuildJob(opts, c.Namespace, c.TTLSeconds)
+	validated, err := c.Clientset.BatchV1().Jobs(c.Namespace).Create(ctx, job, metav1.CreateOptions{
+		DryRun: []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dry-run batch, namespace: %s, image: %s", c.Namespace, opts.Image)
+	}
+	// Decoded objects lose their type information. Restore it for printing.
+	validated.TypeMeta = job.TypeMeta
+	return validated, nil
+}
+
+// List returns the Jobs created by jctl, newest first.
+func (c *jobCli) List(ctx context.Context, opts ListOptions) ([]batchv1.Job, error) {
+	ns := c.Namespace
+	if opts.AllNamespaces {
+		ns = metav1.NamespaceAll
+	}
+	jobs, err := c.Clientset.BatchV1().Jobs(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.selector(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list jobs, namespace: %s", ns)
+	}
+	ret := jobs.Items
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[j].CreationTimestamp.Before(&ret[i].CreationTimestamp)
+	})
+	return ret, nil
+}
+
+// Delete deletes the Job together with its pods.
+func (c *jobCli) Delete(ctx context.Context, name string) error {
+	return c.delete(ctx, name, metav1.DeletePropagationBackground)
+}
+
+func (c *jobCli) delete(ctx context.Context, name string, propagation metav1.DeletionPropagation) error {
+	err := c.Clientset.BatchV1().Jobs(c.Namespace).Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete job, namespace: %s, name: %s", c.Namespace, name)
+	}
+	c.log.Printf("job deleted, name: %s\n", name)
+	return nil
+}
+
+// BuildJob returns the Job which Create submits. It needs no cluster
+// access, so the manifest can be rendered without a kubeconfig.
+func BuildJob(opts JobOptions, namespace string, ttlSec int32) *batchv1.Job {
+	job := &batchv1.Job{
 		TypeMeta: metav1.TypeMeta{APIVersion: batchv1.SchemeGroupVersion.String(), Kind: "Job"},
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: jobName,
 			Namespace:    namespace,
+			Labels:       opts.Origin.labels(),
+			Annotations:  opts.Origin.annotations(),
 		},
 		Spec: batchv1.JobSpec{
 			Template: corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
-							Name:  jobName,
-							Image: image,
+							Name:      jobName,
+							Image:     opts.Image,
+							Args:      opts.Args,
+							Env:       opts.Env,
+							EnvFrom:   opts.EnvFrom,
+							Resources: opts.Resources,
 						},
 					},
-					ImagePullSecrets: []corev1.LocalObjectReference{{Name: imagePullSecretName}},
+					ImagePullSecrets: opts.ImagePullSecrets,
 					RestartPolicy:    corev1.RestartPolicyNever,
 				},
 			},
 			TTLSecondsAfterFinished: &ttlSec,
+			Parallelism:             opts.Parallelism,
+			Completions:             opts.Completions,
+			BackoffLimit:            opts.BackoffLimit,
+			ActiveDeadlineSeconds:   opts.ActiveDeadlineSeconds,
+			PodFailurePolicy:        opts.PodFailurePolicy,
 		},
 	}
+	opts.Scheduling.apply(&job.Spec.Template.Spec)
+	applyMounts(&job.Spec.Template.Spec, &job.Spec.Template.Spec.Containers[0], opts.Mounts)
+	if opts.Uploads != nil {
+		opts.Uploads.apply(&job.Spec.Template.Spec, &job.Spec.Template.Spec.Containers[0])
+	}
+	if opts.Artifacts != nil {
+		opts.Artifacts.apply(&job.Spec.Template.Spec, &job.Spec.Template.Spec.Containers[0])
+	}
+	if opts.ImagePullSecrets == nil {
+		job.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: imagePullSecretName}}
+	}
+	if opts.RestartPolicy != "" {
+		job.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicy(opts.RestartPolicy)
+	}
+	if opts.CompletionMode != "" {
+		mode := batchv1.CompletionMode(opts.CompletionMode)
+		job.Spec.CompletionMode = &mode
+	}
+	return job
 }
 
-func isFinished(j *batchv1.Job) bool {
-	for _, c := range j.Status.Conditions {
+// finishedCondition returns the Complete or Failed condition of the Job,
+// or nil while the Job is still running.
+func finishedCondition(j *batchv1.Job) *batchv1.JobCondition {
+	for i, c := range j.Status.Conditions {
 		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return &j.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// exitCode picks the exit code of the most recently terminated container
+// among the pods of a failed Job. With restart policy OnFailure, the last
+// termination of a container waiting for restart counts too.
+func exitCode(pods []corev1.Pod) int {
+	var latest *corev1.ContainerStateTerminated
+	for _, p := range pods {
+		for _, s := range p.Status.ContainerStatuses {
+			t := s.State.Terminated
+			if t == nil {
+				t = s.LastTerminationState.Terminated
+			}
+			if s.Name != jobName || t == nil {
+				continue
+			}
+			if latest == nil || t.FinishedAt.After(latest.FinishedAt.Time) {
+				latest = t
+			}
+		}
+	}
+	switch {
+	case latest == nil:
+		return ExitCodeJobFailed
+	case latest.Reason == "OOMKilled":
+		return ExitCodeOOMKilled
+	case latest.ExitCode != 0:
+		return int(latest.ExitCode)
+	default:
+		return ExitCodeJobFailed
+	}
+}
+
+// quoteArgs formats args so that they can be pasted into a POSIX shell.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.IndexFunc(a, needsQuote) == -1 {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'"'"'`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func needsQuote(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return false
+	case strings.ContainsRune("@%+=:,./-_", r):
+		return false
+	}
+	return true
+}
+
+// Duration returns how long the Job ran, or has been running so far.
+// It is zero until the Job starts.
+func Duration(j *batchv1.Job) time.Duration {
+	if j.Status.StartTime == nil {
+		return 0
+	}
+	if j.Status.CompletionTime != nil {
+		return j.Status.CompletionTime.Sub(j.Status.StartTime.Time)
+	}
+	if cond := finishedCondition(j); cond != nil {
+		return cond.LastTransitionTime.Sub(j.Status.StartTime.Time)
+	}
+	return time.Since(j.Status.StartTime.Time)
+}
+
+// Image returns the image of the jctl container of the Job.
+func Image(j *batchv1.Job) string {
+	for _, c := range j.Spec.Template.Spec.Containers {
+		if c.Name == jobName {
+			return c.Image
+		}
+	}
+	return ""
+}
+
+// Status returns Complete, Failed or Running.
+func Status(j *batchv1.Job) string {
+	if cond := finishedCondition(j); cond != nil {
+		return string(cond.Type)
+	}
+	return "Running"
+}
+
+// OrNone returns s, or <none> when s is empty, for printing optional values.
+func OrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
+// restartCount returns how many times the Job container of the pod has
+// restarted.
+func restartCount(p *corev1.Pod) int32 {
+	for _, s := range p.Status.ContainerStatuses {
+		if s.Name == jobName {
+			return s.RestartCount
+		}
+	}
+	return 0
+}
+
+func isStarted(p *corev1.Pod) bool {
+	for _, s := range p.Status.ContainerStatuses {
+		if s.Name == jobName && (s.State.Running != nil || s.State.Terminated != nil) {
 			return true
 		}
 	}
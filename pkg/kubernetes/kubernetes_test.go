@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExitCode(t *testing.T) {
+	now := time.Now()
+	terminated := func(code int32, reason string, finished time.Duration) *corev1.ContainerStateTerminated {
+		return &corev1.ContainerStateTerminated{ExitCode: code, Reason: reason, FinishedAt: metav1.NewTime(now.Add(finished))}
+	}
+	pod := func(statuses ...corev1.ContainerStatus) corev1.Pod {
+		return corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: statuses}}
+	}
+	tests := map[string]struct {
+		pods []corev1.Pod
+		want int
+	}{
+		"exit code": {
+			pods: []corev1.Pod{pod(corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(3, "Error", 0)}})},
+			want: 3,
+		},
+		"oom killed": {
+			pods: []corev1.Pod{pod(corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(137, "OOMKilled", 0)}})},
+			want: ExitCodeOOMKilled,
+		},
+		"evicted without terminated state": {
+			pods: []corev1.Pod{{Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}}},
+			want: ExitCodeJobFailed,
+		},
+		"no pods": {
+			want: ExitCodeJobFailed,
+		},
+		"last termination on restart": {
+			pods: []corev1.Pod{pod(corev1.ContainerStatus{
+				Name:                 jobName,
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+				LastTerminationState: corev1.ContainerState{Terminated: terminated(2, "Error", 0)},
+				RestartCount:         3,
+			})},
+			want: 2,
+		},
+		"latest of several pods": {
+			pods: []corev1.Pod{
+				pod(corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(4, "Error", 2*time.Minute)}}),
+				pod(corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(5, "Error", 3*time.Minute)}}),
+				pod(corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(6, "Error", time.Minute)}}),
+			},
+			want: 5,
+		},
+		"other containers are ignored": {
+			pods: []corev1.Pod{pod(
+				corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(7, "Error", 0)}},
+				corev1.ContainerStatus{Name: artifactsName, State: corev1.ContainerState{Terminated: terminated(1, "Error", time.Minute)}},
+			)},
+			want: 7,
+		},
+		"exit code 0 of a failed job": {
+			pods: []corev1.Pod{pod(corev1.ContainerStatus{Name: jobName, State: corev1.ContainerState{Terminated: terminated(0, "Completed", 0)}})},
+			want: ExitCodeJobFailed,
+		},
+	}
+	for name, te := range tests {
+		if got := exitCode(te.pods); got != te.want {
+			t.Errorf("[%s] got: %d, want: %d", name, got, te.want)
+		}
+	}
+}
+
+func TestRestartCount(t *testing.T) {
+	p := &corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+		{Name: artifactsName, RestartCount: 5},
+		{Name: jobName, RestartCount: 2, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+	}}}
+	if got := restartCount(p); got != 2 {
+		t.Errorf("got: %d, want: 2", got)
+	}
+	if got := restartCount(&corev1.Pod{}); got != 0 {
+		t.Errorf("got: %d, want: 0 for a pod without statuses", got)
+	}
+}
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	labelPrefix = "jctl.toshi0607.github.io/"
+
+	// LabelManagedBy is set to "jctl" on every Job jctl creates.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// LabelProgram is the md5 hash of the importpath of the program, since
+	// importpaths are often too long for a label value.
+	LabelProgram = labelPrefix + "program"
+	LabelUser    = labelPrefix + "user"
+	LabelCommit  = labelPrefix + "commit"
+	LabelVersion = labelPrefix + "version"
+	// AnnotationImportPath is the importpath of the program.
+	AnnotationImportPath = labelPrefix + "importpath"
+
+	managedBy           = "jctl"
+	maxLabelValueLength = 63
+)
+
+// Origin describes where a Job comes from. It is recorded in the labels
+// and annotations of the Job so that jctl can find it later.
+type Origin struct {
+	ImportPath string
+	User       string
+	Commit     string
+	Version    string
+}
+
+// ListOptions filter the Jobs returned by List.
+type ListOptions struct {
+	// AllNamespaces lists Jobs of every namespace instead of the namespace
+	// of the JobCli.
+	AllNamespaces bool
+	// ImportPath limits the Jobs to the ones running the program.
+	ImportPath string
+}
+
+func (o Origin) labels() map[string]string {
+	l := map[string]string{LabelManagedBy: managedBy}
+	if o.ImportPath != "" {
+		l[LabelProgram] = ProgramHash(o.ImportPath)
+	}
+	for k, v := range map[string]string{
+		LabelUser:    o.User,
+		LabelCommit:  o.Commit,
+		LabelVersion: o.Version,
+	} {
+		if v := labelValue(v); v != "" {
+			l[k] = v
+		}
+	}
+	return l
+}
+
+func (o Origin) annotations() map[string]string {
+	if o.ImportPath == "" {
+		return nil
+	}
+	return map[string]string{AnnotationImportPath: o.ImportPath}
+}
+
+// OriginOf reads the Origin back from a Job created by jctl.
+func OriginOf(j *batchv1.Job) Origin {
+	return Origin{
+		ImportPath: j.Annotations[AnnotationImportPath],
+		User:       j.Labels[LabelUser],
+		Commit:     j.Labels[LabelCommit],
+		Version:    j.Labels[LabelVersion],
+	}
+}
+
+// ProgramHash returns the value of LabelProgram for importpath.
+func ProgramHash(importpath string) string {
+	hasher := md5.New()
+	hasher.Write([]byte(importpath))
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+func (o ListOptions) selector() string {
+	s := labels.Set{LabelManagedBy: managedBy}
+	if o.ImportPath != "" {
+		s[LabelProgram] = ProgramHash(o.ImportPath)
+	}
+	return s.String()
+}
+
+// labelValue makes v a valid label value by replacing invalid characters
+// with "_" and trimming it to the allowed length.
+func labelValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, v)
+	if len(v) > maxLabelValueLength {
+		v = v[:maxLabelValueLength]
+	}
+	return strings.Trim(v, "-_.")
+}
@@ -0,0 +1,96 @@
+package kubernetes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOrigin_labels(t *testing.T) {
+	tests := map[string]struct {
+		origin          Origin
+		wantLabels      map[string]string
+		wantAnnotations map[string]string
+	}{
+		"empty": {
+			origin:     Origin{},
+			wantLabels: map[string]string{LabelManagedBy: "jctl"},
+		},
+		"full": {
+			origin: Origin{
+				ImportPath: "github.com/toshi0607/jctl/testdata/cmd/hello_world",
+				User:       "toshi0607",
+				Commit:     "0123abc",
+				Version:    "v1.0.0",
+			},
+			wantLabels: map[string]string{
+				LabelManagedBy: "jctl",
+				LabelProgram:   ProgramHash("github.com/toshi0607/jctl/testdata/cmd/hello_world"),
+				LabelUser:      "toshi0607",
+				LabelCommit:    "0123abc",
+				LabelVersion:   "v1.0.0",
+			},
+			wantAnnotations: map[string]string{
+				AnnotationImportPath: "github.com/toshi0607/jctl/testdata/cmd/hello_world",
+			},
+		},
+		"invalid values": {
+			origin: Origin{User: "Toshi Ueda <toshi@example.com>", Version: "v1.0.0-dirty+"},
+			wantLabels: map[string]string{
+				LabelManagedBy: "jctl",
+				LabelUser:      "Toshi_Ueda__toshi_example.com",
+				LabelVersion:   "v1.0.0-dirty",
+			},
+		},
+	}
+	for name, te := range tests {
+		if got := te.origin.labels(); !reflect.DeepEqual(got, te.wantLabels) {
+			t.Errorf("[%s] labels got: %v, want: %v", name, got, te.wantLabels)
+		}
+		if got := te.origin.annotations(); !reflect.DeepEqual(got, te.wantAnnotations) {
+			t.Errorf("[%s] annotations got: %v, want: %v", name, got, te.wantAnnotations)
+		}
+
+		j := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+			Labels:      te.origin.labels(),
+			Annotations: te.origin.annotations(),
+		}}
+		want := te.origin
+		want.User, want.Commit, want.Version = labelValue(want.User), labelValue(want.Commit), labelValue(want.Version)
+		if got := OriginOf(j); got != want {
+			t.Errorf("[%s] OriginOf got: %+v, want: %+v", name, got, want)
+		}
+	}
+}
+
+func TestListOptions_selector(t *testing.T) {
+	if got, want := (ListOptions{}).selector(), LabelManagedBy+"=jctl"; got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+	got := ListOptions{ImportPath: "example.com/cmd/batch"}.selector()
+	if want := LabelProgram + "=" + ProgramHash("example.com/cmd/batch"); !strings.Contains(got, want) {
+		t.Errorf("got: %s, want it to contain: %s", got, want)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		want  string
+	}{
+		"valid":              {value: "v1.2.3", want: "v1.2.3"},
+		"invalid characters": {value: "a b/c", want: "a_b_c"},
+		"trimmed ends":       {value: "-v1.2.3+", want: "v1.2.3"},
+		"too long":           {value: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+		"trimmed after cut":  {value: strings.Repeat("a", 62) + "-b", want: strings.Repeat("a", 62)},
+		"only invalid":       {value: "++", want: ""},
+	}
+	for name, te := range tests {
+		if got := labelValue(te.value); got != te.want {
+			t.Errorf("[%s] got: %q, want: %q", name, got, te.want)
+		}
+	}
+}
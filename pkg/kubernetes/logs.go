@@ -0,0 +1,90 @@
+package kubernetes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Logs prints the logs of the pods of the Job. With follow, it keeps
+// streaming until the Job finishes, like Attach does.
+func (c *jobCli) Logs(ctx context.Context, name string, follow bool) error {
+	if follow {
+		return c.Attach(ctx, name)
+	}
+	if _, err := c.Clientset.BatchV1().Jobs(c.Namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to get job, namespace: %s, name: %s", c.Namespace, name)
+	}
+
+	pods, err := c.Clientset.CoreV1().Pods(c.Namespace).List(ctx, podListOptions(name))
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods, namespace: %s, job: %s", c.Namespace, name)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	for i := range pods.Items {
+		p := &pods.Items[i]
+		if !isStarted(p) {
+			continue
+		}
+		if err := c.streamLogs(ctx, p.Name, false); err != nil {
+			return errors.Wrapf(err, "failed to get logs, pod: %s", p.Name)
+		}
+	}
+	return nil
+}
+
+func (c *jobCli) streamLogs(ctx context.Context, pod string, follow bool) error {
+	req := c.Clientset.CoreV1().Pods(c.Namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: jobName,
+		Follow:    follow,
+	})
+	rc, err := req.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r := bufio.NewReader(rc)
+	for {
+		line, err := r.ReadString('\n')
+		if line != "" {
+			c.logs.writeLine(pod, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// logStreamer serializes log lines of concurrently running pods so that
+// lines of different pods are never interleaved.
+type logStreamer struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (s *logStreamer) writeLine(pod, line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if line[len(line)-1] != '\n' {
+		line += "\n"
+	}
+	fmt.Fprintf(s.out, "[%s] %s", pod, line)
+}
+
+func podListOptions(job string) metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: batchv1.JobNameLabel + "=" + job}
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const mountSyntax = "secret:NAME:PATH, configmap:NAME:PATH, pvc:CLAIM:PATH[:ro] or emptydir:PATH[:SIZE]"
+
+// Mount is a volume of the pod mounted into the Job container.
+type Mount struct {
+	Volume    corev1.VolumeSource
+	MountPath string
+	ReadOnly  bool
+}
+
+// ParseMount parses a mount written as secret:NAME:PATH,
+// configmap:NAME:PATH, pvc:CLAIM:PATH[:ro] or emptydir:PATH[:SIZE].
+func ParseMount(m string) (Mount, error) {
+	parts := strings.Split(m, ":")
+	invalid := func(format string, args ...interface{}) (Mount, error) {
+		return Mount{}, errors.Errorf("invalid mount %q, %s", m, fmt.Sprintf(format, args...))
+	}
+	if len(parts) < 2 {
+		return invalid("want %s", mountSyntax)
+	}
+
+	var mount Mount
+	switch kind, args := parts[0], parts[1:]; kind {
+	case "secret", "configmap":
+		if len(args) != 2 {
+			return invalid("want %s:NAME:PATH", kind)
+		}
+		if errs := validation.IsDNS1123Subdomain(args[0]); len(errs) != 0 {
+			return invalid("%s", strings.Join(errs, ", "))
+		}
+		if kind == "secret" {
+			mount.Volume.Secret = &corev1.SecretVolumeSource{SecretName: args[0]}
+		} else {
+			mount.Volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: args[0]},
+			}
+		}
+		mount.MountPath = args[1]
+		mount.ReadOnly = true
+	case "pvc":
+		if len(args) != 2 && (len(args) != 3 || args[2] != "ro") {
+			return invalid("want pvc:CLAIM:PATH[:ro]")
+		}
+		if errs := validation.IsDNS1123Subdomain(args[0]); len(errs) != 0 {
+			return invalid("%s", strings.Join(errs, ", "))
+		}
+		mount.ReadOnly = len(args) == 3
+		mount.Volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: args[0],
+			ReadOnly:  mount.ReadOnly,
+		}
+		mount.MountPath = args[1]
+	case "emptydir":
+		if len(args) > 2 {
+			return invalid("want emptydir:PATH[:SIZE]")
+		}
+		mount.Volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		if len(args) == 2 {
+			size, err := resource.ParseQuantity(args[1])
+			if err != nil {
+				return invalid("%v", err)
+			}
+			mount.Volume.EmptyDir.SizeLimit = &size
+		}
+		mount.MountPath = args[0]
+	default:
+		return invalid("unknown kind %s, want %s", kind, mountSyntax)
+	}
+
+	if !path.IsAbs(mount.MountPath) {
+		return invalid("path must be absolute")
+	}
+	mount.MountPath = path.Clean(mount.MountPath)
+	return mount, nil
+}
+
+// ValidatePaths checks that no two mounts, uploads or the artifacts,
+// which may be nil, share a path in the container.
+func ValidatePaths(mounts []Mount, uploads []Upload, artifacts *Artifacts) error {
+	var paths []string
+	for _, m := range mounts {
+		paths = append(paths, m.MountPath)
+	}
+	for _, u := range uploads {
+		paths = append(paths, u.Remote)
+	}
+	if artifacts != nil {
+		paths = append(paths, artifacts.Path)
+	}
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if seen[p] {
+			return errors.Errorf("path %s is mounted more than once", p)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// applyMounts adds the volumes to the pod and mounts them into the
+// container. Volumes are named by their order since names of Secrets and
+// claims may be longer than volume names are allowed to be.
+func applyMounts(spec *corev1.PodSpec, c *corev1.Container, mounts []Mount) {
+	for i, m := range mounts {
+		name := fmt.Sprintf("jctl-mount-%d", i)
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name:         name,
+			VolumeSource: m.Volume,
+		})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      name,
+			MountPath: m.MountPath,
+			ReadOnly:  m.ReadOnly,
+		})
+	}
+}
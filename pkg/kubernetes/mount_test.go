@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseMount(t *testing.T) {
+	size := resource.MustParse("1Gi")
+	tests := map[string]struct {
+		mount   string
+		want    Mount
+		wantErr bool
+	}{
+		"secret": {
+			mount: "secret:creds:/etc/creds",
+			want: Mount{
+				Volume:    corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "creds"}},
+				MountPath: "/etc/creds",
+				ReadOnly:  true,
+			},
+		},
+		"configmap": {
+			mount: "configmap:settings:/etc/settings/",
+			want: Mount{
+				Volume: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "settings"},
+				}},
+				MountPath: "/etc/settings",
+				ReadOnly:  true,
+			},
+		},
+		"read-only pvc": {
+			mount: "pvc:datasets:/data:ro",
+			want: Mount{
+				Volume: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: "datasets",
+					ReadOnly:  true,
+				}},
+				MountPath: "/data",
+				ReadOnly:  true,
+			},
+		},
+		"emptydir with size": {
+			mount: "emptydir:/scratch:1Gi",
+			want: Mount{
+				Volume:    corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{SizeLimit: &size}},
+				MountPath: "/scratch",
+			},
+		},
+		"relative path":      {mount: "emptydir:scratch", wantErr: true},
+		"unknown kind":       {mount: "hostpath:/var:/var", wantErr: true},
+		"invalid secret":     {mount: "secret:Creds:/etc/creds", wantErr: true},
+		"invalid pvc option": {mount: "pvc:datasets:/data:rw", wantErr: true},
+		"invalid size":       {mount: "emptydir:/scratch:big", wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := ParseMount(te.mount)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %+v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	mount := func(p string) Mount { return Mount{MountPath: p} }
+	upload := func(p string) Upload { return Upload{Remote: p} }
+	tests := map[string]struct {
+		mounts    []Mount
+		uploads   []Upload
+		artifacts *Artifacts
+		wantErr   bool
+	}{
+		"none":              {},
+		"distinct":          {mounts: []Mount{mount("/data")}, uploads: []Upload{upload("/config")}, artifacts: &Artifacts{Path: "/out"}},
+		"mounts":            {mounts: []Mount{mount("/data"), mount("/data")}, wantErr: true},
+		"upload and mount":  {mounts: []Mount{mount("/data")}, uploads: []Upload{upload("/data")}, wantErr: true},
+		"artifacts":         {mounts: []Mount{mount("/out")}, artifacts: &Artifacts{Path: "/out"}, wantErr: true},
+		"upload, artifacts": {uploads: []Upload{upload("/out")}, artifacts: &Artifacts{Path: "/out"}, wantErr: true},
+	}
+	for name, te := range tests {
+		err := ValidatePaths(te.mounts, te.uploads, te.artifacts)
+		if te.wantErr && err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+		if !te.wantErr && err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+		}
+	}
+}
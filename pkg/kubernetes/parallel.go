@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// ValidateParallelism checks the parallelism, completions and completion
+// mode of the Job. Nil values are left to the defaults of the cluster.
+func ValidateParallelism(parallelism, completions *int32, mode string) error {
+	if parallelism != nil && *parallelism < 0 {
+		return errors.Errorf("invalid parallelism %d, must not be negative", *parallelism)
+	}
+	if completions != nil && *completions < 0 {
+		return errors.Errorf("invalid completions %d, must not be negative", *completions)
+	}
+	switch batchv1.CompletionMode(mode) {
+	case "", batchv1.NonIndexedCompletion:
+	case batchv1.IndexedCompletion:
+		if completions == nil {
+			return errors.New("completions is required for Indexed completion mode")
+		}
+	default:
+		return errors.Errorf("invalid completion mode %q, want %s or %s", mode, batchv1.NonIndexedCompletion, batchv1.IndexedCompletion)
+	}
+	return nil
+}
+
+// isParallel reports whether the Job may run more than one pod at a time
+// or to completion, which is when its progress is worth reporting.
+func isParallel(j *batchv1.Job) bool {
+	return (j.Spec.Parallelism != nil && *j.Spec.Parallelism > 1) ||
+		(j.Spec.Completions != nil && *j.Spec.Completions > 1)
+}
+
+// progress summarizes the pods of the Job, with the completed indexes for
+// Indexed Jobs.
+func progress(j *batchv1.Job) string {
+	p := fmt.Sprintf("active: %d, succeeded: %d, failed: %d", j.Status.Active, j.Status.Succeeded, j.Status.Failed)
+	if j.Spec.Completions != nil {
+		p = fmt.Sprintf("%s, completions: %d", p, *j.Spec.Completions)
+	}
+	if j.Spec.CompletionMode != nil && *j.Spec.CompletionMode == batchv1.IndexedCompletion {
+		p = fmt.Sprintf("%s, completed indexes: %s", p, OrNone(j.Status.CompletedIndexes))
+		if j.Status.FailedIndexes != nil {
+			p = fmt.Sprintf("%s, failed indexes: %s", p, OrNone(*j.Status.FailedIndexes))
+		}
+	}
+	return p
+}
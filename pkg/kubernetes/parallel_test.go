@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+func TestValidateParallelism(t *testing.T) {
+	n := func(i int32) *int32 { return &i }
+	tests := map[string]struct {
+		parallelism *int32
+		completions *int32
+		mode        string
+		wantErr     bool
+	}{
+		"defaults":                {},
+		"parallel":                {parallelism: n(3), completions: n(10)},
+		"non indexed":             {completions: n(10), mode: "NonIndexed"},
+		"indexed":                 {parallelism: n(3), completions: n(10), mode: "Indexed"},
+		"zero parallelism":        {parallelism: n(0)},
+		"indexed w/o completions": {parallelism: n(3), mode: "Indexed", wantErr: true},
+		"negative parallelism":    {parallelism: n(-1), wantErr: true},
+		"negative completions":    {completions: n(-1), wantErr: true},
+		"unknown mode":            {completions: n(10), mode: "indexed", wantErr: true},
+	}
+	for name, te := range tests {
+		err := ValidateParallelism(te.parallelism, te.completions, te.mode)
+		if te.wantErr && err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+		if !te.wantErr && err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	n := func(i int32) *int32 { return &i }
+	indexed := batchv1.IndexedCompletion
+	failedIndexes := "3"
+	tests := map[string]struct {
+		spec   batchv1.JobSpec
+		status batchv1.JobStatus
+		want   string
+	}{
+		"parallelism only": {
+			spec:   batchv1.JobSpec{Parallelism: n(3)},
+			status: batchv1.JobStatus{Active: 2, Succeeded: 4, Failed: 1},
+			want:   "active: 2, succeeded: 4, failed: 1",
+		},
+		"completions": {
+			spec:   batchv1.JobSpec{Parallelism: n(3), Completions: n(10)},
+			status: batchv1.JobStatus{Active: 3, Succeeded: 5, Failed: 2},
+			want:   "active: 3, succeeded: 5, failed: 2, completions: 10",
+		},
+		"indexed, none completed": {
+			spec:   batchv1.JobSpec{Completions: n(5), CompletionMode: &indexed},
+			status: batchv1.JobStatus{Active: 1},
+			want:   "active: 1, succeeded: 0, failed: 0, completions: 5, completed indexes: <none>",
+		},
+		"indexed with failed indexes": {
+			spec:   batchv1.JobSpec{Completions: n(5), CompletionMode: &indexed},
+			status: batchv1.JobStatus{Active: 1, Succeeded: 3, Failed: 1, CompletedIndexes: "0-2", FailedIndexes: &failedIndexes},
+			want:   "active: 1, succeeded: 3, failed: 1, completions: 5, completed indexes: 0-2, failed indexes: 3",
+		},
+	}
+	for name, te := range tests {
+		j := &batchv1.Job{Spec: te.spec, Status: te.status}
+		if got := progress(j); got != te.want {
+			t.Errorf("[%s] got: %q, want: %q", name, got, te.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var podFailurePolicyActions = []batchv1.PodFailurePolicyAction{
+	batchv1.PodFailurePolicyActionFailJob,
+	batchv1.PodFailurePolicyActionIgnore,
+	batchv1.PodFailurePolicyActionCount,
+}
+
+// ParsePodFailureRule parses a rule of the pod failure policy written as
+// ACTION:exit-code=CODE[,...], ACTION:exit-code!=CODE[,...] or
+// ACTION:disruption, for example FailJob:exit-code=2 or Ignore:disruption.
+func ParsePodFailureRule(r string) (batchv1.PodFailurePolicyRule, error) {
+	i := strings.Index(r, ":")
+	if i <= 0 {
+		return batchv1.PodFailurePolicyRule{}, errors.Errorf("invalid pod failure rule %q, want ACTION:exit-code=CODE[,...] or ACTION:disruption", r)
+	}
+	action, cond := batchv1.PodFailurePolicyAction(r[:i]), r[i+1:]
+	if !isPodFailurePolicyAction(action) {
+		return batchv1.PodFailurePolicyRule{}, errors.Errorf("invalid action of pod failure rule %q, want one of %s", r, joinActions())
+	}
+	rule := batchv1.PodFailurePolicyRule{Action: action}
+
+	if cond == "disruption" {
+		rule.OnPodConditions = []batchv1.PodFailurePolicyOnPodConditionsPattern{{
+			Type:   corev1.DisruptionTarget,
+			Status: corev1.ConditionTrue,
+		}}
+		return rule, nil
+	}
+
+	op := batchv1.PodFailurePolicyOnExitCodesOpIn
+	codes := strings.TrimPrefix(cond, "exit-code=")
+	if strings.HasPrefix(cond, "exit-code!=") {
+		op = batchv1.PodFailurePolicyOnExitCodesOpNotIn
+		codes = strings.TrimPrefix(cond, "exit-code!=")
+	} else if codes == cond {
+		return batchv1.PodFailurePolicyRule{}, errors.Errorf("invalid condition of pod failure rule %q, want exit-code=CODE[,...], exit-code!=CODE[,...] or disruption", r)
+	}
+	var values []int32
+	for _, c := range strings.Split(codes, ",") {
+		v, err := strconv.ParseInt(c, 10, 32)
+		if err != nil {
+			return batchv1.PodFailurePolicyRule{}, errors.Errorf("invalid exit code %q in pod failure rule %q", c, r)
+		}
+		values = append(values, int32(v))
+	}
+	// The API server requires the values to be ordered and unique.
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	values = uniqueCodes(values)
+	container := jobName
+	rule.OnExitCodes = &batchv1.PodFailurePolicyOnExitCodesRequirement{
+		ContainerName: &container,
+		Operator:      op,
+		Values:        values,
+	}
+	return rule, nil
+}
+
+// ValidateRetryPolicy checks the restart policy of the pods and that the
+// pod failure policy can be used with it.
+func ValidateRetryPolicy(restartPolicy string, pfp *batchv1.PodFailurePolicy) error {
+	switch corev1.RestartPolicy(restartPolicy) {
+	case "", corev1.RestartPolicyNever, corev1.RestartPolicyOnFailure:
+	default:
+		return errors.Errorf("invalid restart policy %q, want %s or %s", restartPolicy, corev1.RestartPolicyNever, corev1.RestartPolicyOnFailure)
+	}
+	if pfp == nil {
+		return nil
+	}
+	if corev1.RestartPolicy(restartPolicy) == corev1.RestartPolicyOnFailure {
+		return errors.New("pod failure policy requires restart policy Never")
+	}
+	for _, r := range pfp.Rules {
+		if r.Action == batchv1.PodFailurePolicyActionFailIndex {
+			// FailIndex needs backoffLimitPerIndex, which jctl doesn't set.
+			return errors.Errorf("action %s of pod failure rule is not supported, want one of %s", r.Action, joinActions())
+		}
+		if !isPodFailurePolicyAction(r.Action) {
+			return errors.Errorf("invalid action of pod failure rule %q, want one of %s", r.Action, joinActions())
+		}
+		if (r.OnExitCodes == nil) == (len(r.OnPodConditions) == 0) {
+			return errors.Errorf("pod failure rule of action %s needs either onExitCodes or onPodConditions", r.Action)
+		}
+		if r.OnExitCodes == nil {
+			continue
+		}
+		seen := map[int32]bool{}
+		for _, v := range r.OnExitCodes.Values {
+			if v == 0 && r.OnExitCodes.Operator == batchv1.PodFailurePolicyOnExitCodesOpIn {
+				return errors.Errorf("pod failure rule of action %s must not match exit code 0 with In", r.Action)
+			}
+			if seen[v] {
+				return errors.Errorf("pod failure rule of action %s has exit code %d more than once", r.Action, v)
+			}
+			seen[v] = true
+		}
+	}
+	return nil
+}
+
+// uniqueCodes removes the repeated values of the sorted exit codes.
+func uniqueCodes(values []int32) []int32 {
+	var u []int32
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			u = append(u, v)
+		}
+	}
+	return u
+}
+
+func isPodFailurePolicyAction(a batchv1.PodFailurePolicyAction) bool {
+	for _, v := range podFailurePolicyActions {
+		if a == v {
+			return true
+		}
+	}
+	return false
+}
+
+func joinActions() string {
+	s := make([]string, len(podFailurePolicyActions))
+	for i, a := range podFailurePolicyActions {
+		s[i] = string(a)
+	}
+	return strings.Join(s, ", ")
+}
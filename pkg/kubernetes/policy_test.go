@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParsePodFailureRule(t *testing.T) {
+	container := jobName
+	tests := map[string]struct {
+		rule    string
+		want    batchv1.PodFailurePolicyRule
+		wantErr bool
+	}{
+		"exit codes": {
+			rule: "FailJob:exit-code=42,2",
+			want: batchv1.PodFailurePolicyRule{
+				Action: batchv1.PodFailurePolicyActionFailJob,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					ContainerName: &container,
+					Operator:      batchv1.PodFailurePolicyOnExitCodesOpIn,
+					Values:        []int32{2, 42},
+				},
+			},
+		},
+		"not in exit codes": {
+			rule: "Count:exit-code!=1",
+			want: batchv1.PodFailurePolicyRule{
+				Action: batchv1.PodFailurePolicyActionCount,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					ContainerName: &container,
+					Operator:      batchv1.PodFailurePolicyOnExitCodesOpNotIn,
+					Values:        []int32{1},
+				},
+			},
+		},
+		"disruption": {
+			rule: "Ignore:disruption",
+			want: batchv1.PodFailurePolicyRule{
+				Action: batchv1.PodFailurePolicyActionIgnore,
+				OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{{
+					Type:   corev1.DisruptionTarget,
+					Status: corev1.ConditionTrue,
+				}},
+			},
+		},
+		"repeated exit codes": {
+			rule: "Ignore:exit-code=3,1,3,1",
+			want: batchv1.PodFailurePolicyRule{
+				Action: batchv1.PodFailurePolicyActionIgnore,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					ContainerName: &container,
+					Operator:      batchv1.PodFailurePolicyOnExitCodesOpIn,
+					Values:        []int32{1, 3},
+				},
+			},
+		},
+		"fail index":        {rule: "FailIndex:exit-code=1", wantErr: true},
+		"unknown action":    {rule: "Retry:exit-code=1", wantErr: true},
+		"unknown condition": {rule: "FailJob:oom", wantErr: true},
+		"invalid exit code": {rule: "FailJob:exit-code=x", wantErr: true},
+		"without action":    {rule: "exit-code=1", wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := ParsePodFailureRule(te.rule)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %+v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
+
+func TestValidateRetryPolicy(t *testing.T) {
+	container := jobName
+	policy := func(action batchv1.PodFailurePolicyAction, op batchv1.PodFailurePolicyOnExitCodesOperator, values ...int32) *batchv1.PodFailurePolicy {
+		return &batchv1.PodFailurePolicy{Rules: []batchv1.PodFailurePolicyRule{{
+			Action:      action,
+			OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{ContainerName: &container, Operator: op, Values: values},
+		}}}
+	}
+	tests := map[string]struct {
+		restartPolicy string
+		pfp           *batchv1.PodFailurePolicy
+		wantErr       bool
+	}{
+		"defaults":            {},
+		"on failure":          {restartPolicy: "OnFailure"},
+		"valid policy":        {restartPolicy: "Never", pfp: policy(batchv1.PodFailurePolicyActionFailJob, batchv1.PodFailurePolicyOnExitCodesOpIn, 1, 2)},
+		"not in exit code 0":  {pfp: policy(batchv1.PodFailurePolicyActionCount, batchv1.PodFailurePolicyOnExitCodesOpNotIn, 0)},
+		"bad restart policy":  {restartPolicy: "Always", wantErr: true},
+		"policy on failure":   {restartPolicy: "OnFailure", pfp: policy(batchv1.PodFailurePolicyActionFailJob, batchv1.PodFailurePolicyOnExitCodesOpIn, 1), wantErr: true},
+		"fail index":          {pfp: policy(batchv1.PodFailurePolicyActionFailIndex, batchv1.PodFailurePolicyOnExitCodesOpIn, 1), wantErr: true},
+		"in exit code 0":      {pfp: policy(batchv1.PodFailurePolicyActionIgnore, batchv1.PodFailurePolicyOnExitCodesOpIn, 0), wantErr: true},
+		"repeated exit codes": {pfp: policy(batchv1.PodFailurePolicyActionFailJob, batchv1.PodFailurePolicyOnExitCodesOpIn, 2, 2), wantErr: true},
+		"no condition":        {pfp: &batchv1.PodFailurePolicy{Rules: []batchv1.PodFailurePolicyRule{{Action: batchv1.PodFailurePolicyActionIgnore}}}, wantErr: true},
+	}
+	for name, te := range tests {
+		err := ValidateRetryPolicy(te.restartPolicy, te.pfp)
+		if te.wantErr && err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+		if !te.wantErr && err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// PullSecretNone given as an image pull secret makes the pod pull images
+// without any, e.g. with workload identity of the nodes.
+const PullSecretNone = "none"
+
+// ParseImagePullSecrets validates the names of image pull secrets. It
+// returns nil for no names, so that the default secret is used, and an
+// empty list for PullSecretNone.
+func ParseImagePullSecrets(names []string) ([]corev1.LocalObjectReference, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	refs := []corev1.LocalObjectReference{}
+	for _, n := range names {
+		if n == PullSecretNone {
+			if len(names) != 1 {
+				return nil, errors.Errorf("image pull secret %q can not be combined with others", PullSecretNone)
+			}
+			return refs, nil
+		}
+		if errs := validation.IsDNS1123Subdomain(n); len(errs) != 0 {
+			return nil, errors.Errorf("invalid image pull secret name %q: %s", n, strings.Join(errs, ", "))
+		}
+		refs = append(refs, corev1.LocalObjectReference{Name: n})
+	}
+	return refs, nil
+}
+
+// DefaultPullSecret returns the name of the image pull secret the Job
+// refers to, or an empty string when it refers to none.
+func DefaultPullSecret(refs []corev1.LocalObjectReference) string {
+	if refs == nil {
+		return imagePullSecretName
+	}
+	if len(refs) == 0 {
+		return ""
+	}
+	return refs[0].Name
+}
+
+// ApplyPullSecret creates the Secret of type kubernetes.io/dockerconfigjson,
+// or replaces its content when it exists and was created by jctl. Secrets
+// managed by others, e.g. by the cluster admin, are left alone.
+func (c *jobCli) ApplyPullSecret(ctx context.Context, name string, dockerConfigJSON []byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: c.Namespace,
+			Labels:    map[string]string{LabelManagedBy: managedBy},
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: dockerConfigJSON},
+	}
+	client := c.Clientset.CoreV1().Secrets(c.Namespace)
+
+	current, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := client.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return errors.Wrapf(err, "failed to create image pull secret, namespace: %s, name: %s", c.Namespace, name)
+		}
+		c.log.Printf("image pull secret created, name: %s\n", name)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get image pull secret, namespace: %s, name: %s", c.Namespace, name)
+	}
+	if err := updatablePullSecret(current); err != nil {
+		return err
+	}
+	current.Data = secret.Data
+	if _, err := client.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to update image pull secret, namespace: %s, name: %s", c.Namespace, name)
+	}
+	c.log.Printf("image pull secret updated, name: %s\n", name)
+	return nil
+}
+
+// updatablePullSecret returns an error unless the existing Secret s is an
+// image pull secret jctl created, and may therefore overwrite.
+func updatablePullSecret(s *corev1.Secret) error {
+	if s.Type != corev1.SecretTypeDockerConfigJson {
+		return errors.Errorf("secret %s exists with type %s, want %s", s.Name, s.Type, corev1.SecretTypeDockerConfigJson)
+	}
+	if s.Labels[LabelManagedBy] != managedBy {
+		return errors.Errorf("secret %s exists and is not managed by jctl, name another image pull secret or remove --create-pull-secret", s.Name)
+	}
+	return nil
+}
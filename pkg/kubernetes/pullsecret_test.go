@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseImagePullSecrets(t *testing.T) {
+	tests := map[string]struct {
+		names   []string
+		want    []corev1.LocalObjectReference
+		wantErr bool
+	}{
+		"default": {},
+		"names": {
+			names: []string{"image-puller", "gcr.io-puller"},
+			want:  []corev1.LocalObjectReference{{Name: "image-puller"}, {Name: "gcr.io-puller"}},
+		},
+		"none": {
+			names: []string{PullSecretNone},
+			want:  []corev1.LocalObjectReference{},
+		},
+		"none with others": {names: []string{"image-puller", PullSecretNone}, wantErr: true},
+		"bad name":         {names: []string{"Image_Puller"}, wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := ParseImagePullSecrets(te.names)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %+v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %#v, want: %#v", name, got, te.want)
+		}
+	}
+}
+
+func TestDefaultPullSecret(t *testing.T) {
+	tests := map[string]struct {
+		refs []corev1.LocalObjectReference
+		want string
+	}{
+		"default": {want: imagePullSecretName},
+		"none":    {refs: []corev1.LocalObjectReference{}, want: ""},
+		"first":   {refs: []corev1.LocalObjectReference{{Name: "a"}, {Name: "b"}}, want: "a"},
+	}
+	for name, te := range tests {
+		if got := DefaultPullSecret(te.refs); got != te.want {
+			t.Errorf("[%s] got: %q, want: %q", name, got, te.want)
+		}
+	}
+}
+
+func TestUpdatablePullSecret(t *testing.T) {
+	secret := func(typ corev1.SecretType, labels map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "image-puller", Labels: labels}, Type: typ}
+	}
+	jctl := map[string]string{LabelManagedBy: managedBy}
+	tests := map[string]struct {
+		secret  *corev1.Secret
+		wantErr bool
+	}{
+		"managed by jctl":    {secret: secret(corev1.SecretTypeDockerConfigJson, jctl)},
+		"managed by others":  {secret: secret(corev1.SecretTypeDockerConfigJson, map[string]string{LabelManagedBy: "helm"}), wantErr: true},
+		"without labels":     {secret: secret(corev1.SecretTypeDockerConfigJson, nil), wantErr: true},
+		"other type of jctl": {secret: secret(corev1.SecretTypeOpaque, jctl), wantErr: true},
+	}
+	for name, te := range tests {
+		err := updatablePullSecret(te.secret)
+		if te.wantErr && err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+		if !te.wantErr && err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package kubernetes
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ParseQuantity parses the quantity of a resource given on the command
+// line, like 500m for cpu or 1Gi for memory.
+func ParseQuantity(name corev1.ResourceName, v string) (resource.Quantity, error) {
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return resource.Quantity{}, errors.Wrapf(err, "invalid %s quantity %q", name, v)
+	}
+	return q, nil
+}
+
+// ValidateResources checks that quantities are positive and that no
+// request exceeds its limit, which the API server would reject only after
+// the image is built and pushed.
+func ValidateResources(r corev1.ResourceRequirements) error {
+	for _, l := range []corev1.ResourceList{r.Requests, r.Limits} {
+		for _, name := range resourceNames(l) {
+			q := l[name]
+			if q.Sign() < 0 {
+				return errors.Errorf("invalid %s quantity %s, must not be negative", name, q.String())
+			}
+		}
+	}
+	for _, name := range resourceNames(r.Requests) {
+		limit, ok := r.Limits[name]
+		if !ok {
+			continue
+		}
+		request := r.Requests[name]
+		if request.Cmp(limit) > 0 {
+			return errors.Errorf("%s request %s exceeds its limit %s", name, request.String(), limit.String())
+		}
+	}
+	return nil
+}
+
+// resourceNames returns the names in l sorted, so that errors are stable.
+func resourceNames(l corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
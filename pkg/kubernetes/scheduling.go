@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Scheduling decides which nodes the pods of the Job run on and under
+// which identity. Empty values are left to the defaults of the cluster.
+type Scheduling struct {
+	ServiceAccountName string
+	NodeSelector       map[string]string
+	Tolerations        []corev1.Toleration
+	Affinity           *corev1.Affinity
+	PriorityClassName  string
+	RuntimeClassName   string
+}
+
+// ParseToleration parses a toleration written like a taint of kubectl
+// taint, KEY[=VALUE][:EFFECT]. Without a value, the taint is tolerated
+// whatever its value is, and without an effect, whatever its effect is.
+func ParseToleration(t string) (corev1.Toleration, error) {
+	var tol corev1.Toleration
+	kv := t
+	if i := strings.LastIndex(t, ":"); i >= 0 {
+		kv = t[:i]
+		tol.Effect = corev1.TaintEffect(t[i+1:])
+	}
+	if i := strings.Index(kv, "="); i >= 0 {
+		tol.Key, tol.Value = kv[:i], kv[i+1:]
+		tol.Operator = corev1.TolerationOpEqual
+	} else {
+		tol.Key = kv
+		tol.Operator = corev1.TolerationOpExists
+	}
+	if err := validateToleration(tol); err != nil {
+		return corev1.Toleration{}, errors.Wrapf(err, "invalid toleration %q, want KEY[=VALUE][:EFFECT]", t)
+	}
+	return tol, nil
+}
+
+// Validate checks the syntax of the names, labels and tolerations, which
+// the API server would reject only after the image is built and pushed.
+func (s Scheduling) Validate() error {
+	for _, n := range []struct{ kind, name string }{
+		{"service account", s.ServiceAccountName},
+		{"priority class", s.PriorityClassName},
+		{"runtime class", s.RuntimeClassName},
+	} {
+		if n.name == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(n.name); len(errs) != 0 {
+			return errors.Errorf("invalid %s name %q: %s", n.kind, n.name, strings.Join(errs, ", "))
+		}
+	}
+	for k, v := range s.NodeSelector {
+		if errs := validation.IsQualifiedName(k); len(errs) != 0 {
+			return errors.Errorf("invalid node selector key %q: %s", k, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return errors.Errorf("invalid node selector value %q of %s: %s", v, k, strings.Join(errs, ", "))
+		}
+	}
+	for _, t := range s.Tolerations {
+		if err := validateToleration(t); err != nil {
+			return errors.Wrapf(err, "invalid toleration of key %q", t.Key)
+		}
+	}
+	return nil
+}
+
+func validateToleration(t corev1.Toleration) error {
+	if t.Key != "" {
+		if errs := validation.IsQualifiedName(t.Key); len(errs) != 0 {
+			return errors.New(strings.Join(errs, ", "))
+		}
+	}
+	switch t.Operator {
+	case "", corev1.TolerationOpEqual:
+		if t.Key == "" {
+			return errors.New("key is required with operator Equal")
+		}
+		if errs := validation.IsValidLabelValue(t.Value); len(errs) != 0 {
+			return errors.New(strings.Join(errs, ", "))
+		}
+	case corev1.TolerationOpExists:
+		if t.Value != "" {
+			return errors.New("value must be empty with operator Exists")
+		}
+	default:
+		return errors.Errorf("unknown operator %s", t.Operator)
+	}
+	switch t.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return errors.Errorf("unknown effect %s, want %s, %s or %s", t.Effect, corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute)
+	}
+	return nil
+}
+
+func (s Scheduling) apply(spec *corev1.PodSpec) {
+	spec.ServiceAccountName = s.ServiceAccountName
+	spec.NodeSelector = s.NodeSelector
+	spec.Tolerations = s.Tolerations
+	spec.Affinity = s.Affinity
+	spec.PriorityClassName = s.PriorityClassName
+	if s.RuntimeClassName != "" {
+		rc := s.RuntimeClassName
+		spec.RuntimeClassName = &rc
+	}
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseToleration(t *testing.T) {
+	tests := map[string]struct {
+		toleration string
+		want       corev1.Toleration
+		wantErr    bool
+	}{
+		"key": {
+			toleration: "dedicated",
+			want:       corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists},
+		},
+		"key and effect": {
+			toleration: "example.com/gpu:NoSchedule",
+			want:       corev1.Toleration{Key: "example.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		},
+		"key and value": {
+			toleration: "dedicated=batch",
+			want:       corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "batch"},
+		},
+		"key, value and effect": {
+			toleration: "dedicated=batch:NoExecute",
+			want: corev1.Toleration{
+				Key:      "dedicated",
+				Operator: corev1.TolerationOpEqual,
+				Value:    "batch",
+				Effect:   corev1.TaintEffectNoExecute,
+			},
+		},
+		"bad effect":    {toleration: "dedicated=batch:NoRun", wantErr: true},
+		"bad key":       {toleration: "dedicated batch:NoSchedule", wantErr: true},
+		"bad value":     {toleration: "dedicated=batch jobs", wantErr: true},
+		"value w/o key": {toleration: "=batch", wantErr: true},
+	}
+	for name, te := range tests {
+		got, err := ParseToleration(te.toleration)
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %+v", name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
+
+func TestScheduling_Validate(t *testing.T) {
+	tests := map[string]struct {
+		scheduling Scheduling
+		wantErr    bool
+	}{
+		"empty": {},
+		"valid": {
+			scheduling: Scheduling{
+				ServiceAccountName: "batch-runner",
+				NodeSelector:       map[string]string{"cloud.google.com/gke-nodepool": "batch", "disktype": "ssd"},
+				Tolerations:        []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+				PriorityClassName:  "low-priority",
+				RuntimeClassName:   "gvisor",
+			},
+		},
+		"bad service account": {
+			scheduling: Scheduling{ServiceAccountName: "Batch_Runner"},
+			wantErr:    true,
+		},
+		"bad priority class": {
+			scheduling: Scheduling{PriorityClassName: "low priority"},
+			wantErr:    true,
+		},
+		"bad runtime class": {
+			scheduling: Scheduling{RuntimeClassName: "-gvisor"},
+			wantErr:    true,
+		},
+		"bad node selector key": {
+			scheduling: Scheduling{NodeSelector: map[string]string{"disk type": "ssd"}},
+			wantErr:    true,
+		},
+		"bad node selector value": {
+			scheduling: Scheduling{NodeSelector: map[string]string{"disktype": "ssd/nvme"}},
+			wantErr:    true,
+		},
+		"bad toleration": {
+			scheduling: Scheduling{Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "batch"}}},
+			wantErr:    true,
+		},
+	}
+	for name, te := range tests {
+		err := te.scheduling.Validate()
+		if te.wantErr && err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+		if !te.wantErr && err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+		}
+	}
+}
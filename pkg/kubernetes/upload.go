@@ -0,0 +1,390 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// InlineUploadLimit is the total size of uploads shipped in a Secret.
+	// Larger uploads are streamed into an init container, since the size
+	// of a Secret is limited to 1MiB.
+	InlineUploadLimit = 512 * 1024
+	// DefaultUploaderImage is the image of the containers jctl adds to the
+	// pod to receive uploads and hand over artifacts. It needs sh and tar.
+	DefaultUploaderImage = "busybox:1.36"
+
+	uploadName     = "jctl-upload"
+	uploadRoot     = "/jctl-upload"
+	uploadDoneFile = ".done"
+	// uploadWaitSec is how long the init container waits for jctl. A pod
+	// retried after jctl exited fails instead of waiting forever.
+	uploadWaitSec = 600
+)
+
+type (
+	// Upload is a local file or directory shipped to the Job at run time.
+	Upload struct {
+		Local  string
+		Remote string
+		Dir    bool
+	}
+
+	// Uploads are the files shipped to the Job, either in a Secret owned by
+	// the Job or through an init container when they are too large.
+	Uploads struct {
+		uploads []Upload
+		files   [][]uploadFile
+		size    int64
+		image   string
+		// secretName is generated when the Secret is created.
+		secretName string
+	}
+
+	uploadFile struct {
+		local string
+		// rel is the path relative to the uploaded directory, empty for a
+		// file upload.
+		rel  string
+		mode os.FileMode
+	}
+)
+
+// ParseUpload parses a file or a directory to upload written as
+// LOCAL=REMOTE, where REMOTE is an absolute path in the container.
+func ParseUpload(u string, dir bool) (Upload, error) {
+	i := strings.Index(u, "=")
+	if i <= 0 || i == len(u)-1 {
+		return Upload{}, errors.Errorf("invalid upload %q, want LOCAL=REMOTE", u)
+	}
+	up := Upload{Local: u[:i], Remote: u[i+1:], Dir: dir}
+	if !path.IsAbs(up.Remote) {
+		return Upload{}, errors.Errorf("invalid upload %q, remote path must be absolute", u)
+	}
+	up.Remote = path.Clean(up.Remote)
+	return up, nil
+}
+
+// NewUploads collects the files to upload. It returns nil for no uploads.
+func NewUploads(uploads []Upload, image string) (*Uploads, error) {
+	if len(uploads) == 0 {
+		return nil, nil
+	}
+	if image == "" {
+		image = DefaultUploaderImage
+	}
+	u := &Uploads{uploads: uploads, image: image}
+	remotes := make(map[string]bool, len(uploads))
+	for _, up := range uploads {
+		if remotes[up.Remote] {
+			return nil, errors.Errorf("%s is uploaded more than once", up.Remote)
+		}
+		remotes[up.Remote] = true
+
+		files, err := collectFiles(up)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			info, err := os.Stat(f.local)
+			if err != nil {
+				return nil, err
+			}
+			u.size += info.Size()
+		}
+		u.files = append(u.files, files)
+	}
+	return u, nil
+}
+
+func collectFiles(up Upload) ([]uploadFile, error) {
+	info, err := os.Stat(up.Local)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find upload")
+	}
+	if info.IsDir() != up.Dir {
+		if up.Dir {
+			return nil, errors.Errorf("%s is not a directory, use --file", up.Local)
+		}
+		return nil, errors.Errorf("%s is a directory, use --dir", up.Local)
+	}
+	if !up.Dir {
+		return []uploadFile{{local: up.Local, mode: info.Mode().Perm()}}, nil
+	}
+
+	files, err := walkFiles(up.Local, "", make(map[string]bool))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read upload directory %s", up.Local)
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("no files to upload in %s", up.Local)
+	}
+	return files, nil
+}
+
+// walkFiles lists the files under dir, with their paths relative to dir
+// joined to rel. Symlinks are followed like the jctldata directory of the
+// image, into directories too. seen holds the directories being walked to
+// stop at symlink loops.
+func walkFiles(dir, rel string, seen map[string]bool) ([]uploadFile, error) {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	if seen[real] {
+		return nil, errors.Errorf("symlink loop at %s", dir)
+	}
+	seen[real] = true
+	defer delete(seen, real)
+
+	var files []uploadFile
+	err = filepath.Walk(real, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		r, err := filepath.Rel(real, p)
+		if err != nil {
+			return err
+		}
+		r = path.Join(rel, filepath.ToSlash(r))
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(p); err != nil {
+				return err
+			}
+			if info.IsDir() {
+				sub, err := walkFiles(p, r, seen)
+				files = append(files, sub...)
+				return err
+			}
+		}
+		files = append(files, uploadFile{local: p, rel: r, mode: info.Mode().Perm()})
+		return nil
+	})
+	return files, err
+}
+
+func (u *Uploads) inline() bool {
+	return u.size <= InlineUploadLimit
+}
+
+func uploadKey(i, j int) string {
+	return fmt.Sprintf("u%d-%d", i, j)
+}
+
+// apply mounts the uploads into the container. The Secret is referred to
+// by a placeholder name until Create generates it, e.g. on dry-run.
+func (u *Uploads) apply(spec *corev1.PodSpec, c *corev1.Container) {
+	if u.inline() {
+		secretName := u.secretName
+		if secretName == "" {
+			secretName = uploadName
+		}
+		for i, up := range u.uploads {
+			name := fmt.Sprintf("%s-%d", uploadName, i)
+			var items []corev1.KeyToPath
+			for j, f := range u.files[i] {
+				p := f.rel
+				if !up.Dir {
+					p = path.Base(up.Remote)
+				}
+				mode := int32(f.mode)
+				items = append(items, corev1.KeyToPath{Key: uploadKey(i, j), Path: p, Mode: &mode})
+			}
+			spec.Volumes = append(spec.Volumes, corev1.Volume{
+				Name: name,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: secretName, Items: items},
+				},
+			})
+			m := corev1.VolumeMount{Name: name, MountPath: up.Remote, ReadOnly: true}
+			if !up.Dir {
+				m.SubPath = path.Base(up.Remote)
+			}
+			c.VolumeMounts = append(c.VolumeMounts, m)
+		}
+		return
+	}
+
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         uploadName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:  uploadName,
+		Image: u.image,
+		Command: []string{"sh", "-c", fmt.Sprintf(
+			`i=0; until [ -f %[1]s/%[2]s ]; do i=$((i+1)); if [ $i -gt %[3]d ]; then echo "no upload from jctl" >&2; exit 1; fi; sleep 1; done`,
+			uploadRoot, uploadDoneFile, uploadWaitSec)},
+		VolumeMounts: []corev1.VolumeMount{{Name: uploadName, MountPath: uploadRoot}},
+	})
+	for i, up := range u.uploads {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      uploadName,
+			MountPath: up.Remote,
+			SubPath:   fmt.Sprintf("u%d", i),
+		})
+	}
+}
+
+// secret returns the Secret holding inline uploads.
+func (u *Uploads) secret(namespace string) (*corev1.Secret, error) {
+	data := make(map[string][]byte)
+	for i := range u.uploads {
+		for j, f := range u.files[i] {
+			b, err := ioutil.ReadFile(f.local)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read upload")
+			}
+			data[uploadKey(i, j)] = b
+		}
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: uploadName + "-",
+			Namespace:    namespace,
+			Labels:       map[string]string{LabelManagedBy: managedBy},
+		},
+		Data: data,
+	}, nil
+}
+
+// writeTar writes the uploads in the layout the container mounts them.
+func (u *Uploads) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for i, up := range u.uploads {
+		root := fmt.Sprintf("u%d", i)
+		dirs := make(map[string]bool)
+		for _, f := range u.files[i] {
+			name := root
+			if up.Dir {
+				name = path.Join(root, f.rel)
+				// Parents come before their children.
+				var parents []string
+				for d := path.Dir(name); !dirs[d] && d != "."; d = path.Dir(d) {
+					dirs[d] = true
+					parents = append([]string{d}, parents...)
+				}
+				for _, d := range parents {
+					if err := tw.WriteHeader(&tar.Header{Name: d + "/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+						return err
+					}
+				}
+			}
+			if err := writeTarFile(tw, name, f); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, f uploadFile) error {
+	file, err := os.Open(f.local)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Size:     info.Size(),
+		Typeflag: tar.TypeReg,
+		Mode:     int64(f.mode),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+func (c *jobCli) createUploadSecret(ctx context.Context, u *Uploads) error {
+	secret, err := u.secret(c.Namespace)
+	if err != nil {
+		return err
+	}
+	created, err := c.Clientset.CoreV1().Secrets(c.Namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create upload secret, namespace: %s", c.Namespace)
+	}
+	u.secretName = created.Name
+	return nil
+}
+
+// ownUploadSecret makes the Job own the Secret so that it is deleted with
+// the Job. The Secret is deleted right away when that fails.
+func (c *jobCli) ownUploadSecret(ctx context.Context, u *Uploads, job *batchv1.Job) error {
+	client := c.Clientset.CoreV1().Secrets(c.Namespace)
+	secret, err := client.Get(ctx, u.secretName, metav1.GetOptions{})
+	if err == nil {
+		secret.OwnerReferences = append(secret.OwnerReferences, metav1.OwnerReference{
+			APIVersion: batchv1.SchemeGroupVersion.String(),
+			Kind:       "Job",
+			Name:       job.Name,
+			UID:        job.UID,
+		})
+		_, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		c.deleteUploadSecret(u)
+		return errors.Wrapf(err, "failed to set owner of upload secret, name: %s", u.secretName)
+	}
+	return nil
+}
+
+func (c *jobCli) deleteUploadSecret(u *Uploads) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := c.Clientset.CoreV1().Secrets(c.Namespace).Delete(ctx, u.secretName, metav1.DeleteOptions{}); err != nil {
+		c.log.Printf("failed to delete upload secret, name: %s, err: %v\n", u.secretName, err)
+	}
+}
+
+// upload streams the uploads into the init container of the pod, then
+// lets the init container finish.
+func (c *jobCli) upload(ctx context.Context, pod string, u *Uploads) error {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(u.writeTar(w))
+	}()
+	defer r.Close()
+
+	cmd := []string{"sh", "-c", fmt.Sprintf("tar -xf - -C %[1]s && touch %[1]s/%[2]s", uploadRoot, uploadDoneFile)}
+	if err := c.exec(ctx, pod, uploadName, cmd, r, nil); err != nil {
+		return err
+	}
+	c.log.Printf("files uploaded, pod: %s\n", pod)
+	return nil
+}
+
+func isUploaderRunning(p *corev1.Pod) bool {
+	for _, s := range p.Status.InitContainerStatuses {
+		if s.Name == uploadName && s.State.Running != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsAttach reports whether jctl has to stay until the uploads are
+// streamed into the Job, which rules out detaching.
+func (u *Uploads) NeedsAttach() bool {
+	return u != nil && !u.inline()
+}
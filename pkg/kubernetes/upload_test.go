@@ -0,0 +1,219 @@
+package kubernetes
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestUploads_writeTar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for p, content := range map[string]string{
+		"input.csv":       "a,b\n",
+		"models/a/b.bin":  "model",
+		"models/top.json": "{}",
+	} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := NewUploads([]Upload{
+		{Local: filepath.Join(dir, "input.csv"), Remote: "/in/input.csv"},
+		{Local: filepath.Join(dir, "models"), Remote: "/models", Dir: true},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.inline() {
+		t.Errorf("small uploads should be inline, size: %d", u.size)
+	}
+
+	var buf bytes.Buffer
+	if err := u.writeTar(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	tr := tar.NewReader(&buf)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, h.Name)
+	}
+	want := []string{"u0", "u1/", "u1/a/", "u1/a/b.bin", "u1/top.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestNewUploads_invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string][]Upload{
+		"missing file":      {{Local: filepath.Join(dir, "missing"), Remote: "/in"}},
+		"directory as file": {{Local: dir, Remote: "/in"}},
+		"empty directory":   {{Local: dir, Remote: "/in", Dir: true}},
+	}
+	for name, uploads := range tests {
+		if _, err := NewUploads(uploads, ""); err == nil {
+			t.Errorf("[%s] want error", name)
+		}
+	}
+}
+
+func TestNewUploads_symlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for p, content := range map[string]string{
+		"upload/input.csv":  "a,b\n",
+		"shared/model.bin":  "model",
+		"shared/a/conf.yml": "a: b\n",
+	} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upload := filepath.Join(dir, "upload")
+	if err := os.Symlink(filepath.Join(dir, "shared"), filepath.Join(upload, "shared")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "shared", "model.bin"), filepath.Join(upload, "model.bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := NewUploads([]Upload{{Local: upload, Remote: "/in", Dir: true}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range u.files[0] {
+		got = append(got, f.rel)
+	}
+	want := []string{"input.csv", "model.bin", "shared/a/conf.yml", "shared/model.bin"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+
+	if err := os.Symlink(upload, filepath.Join(upload, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewUploads([]Upload{{Local: upload, Remote: "/in", Dir: true}}, ""); err == nil {
+		t.Error("want error on a symlink loop")
+	}
+}
+
+func TestBuildJob_uploads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for p, size := range map[string]int{
+		"input.csv":       4,
+		"models/a.bin":    8,
+		"large/model.bin": InlineUploadLimit + 1,
+	} {
+		p = filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, make([]byte, size), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mode := int32(0640)
+
+	tests := map[string]struct {
+		uploads        []Upload
+		wantVolumes    []corev1.Volume
+		wantMounts     []corev1.VolumeMount
+		wantInitImages []string
+	}{
+		"inline": {
+			uploads: []Upload{
+				{Local: filepath.Join(dir, "input.csv"), Remote: "/in/input.csv"},
+				{Local: filepath.Join(dir, "models"), Remote: "/models", Dir: true},
+			},
+			wantVolumes: []corev1.Volume{
+				{Name: "jctl-upload-0", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+					SecretName: "jctl-upload",
+					Items:      []corev1.KeyToPath{{Key: "u0-0", Path: "input.csv", Mode: &mode}},
+				}}},
+				{Name: "jctl-upload-1", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+					SecretName: "jctl-upload",
+					Items:      []corev1.KeyToPath{{Key: "u1-0", Path: "a.bin", Mode: &mode}},
+				}}},
+			},
+			wantMounts: []corev1.VolumeMount{
+				{Name: "jctl-upload-0", MountPath: "/in/input.csv", SubPath: "input.csv", ReadOnly: true},
+				{Name: "jctl-upload-1", MountPath: "/models", ReadOnly: true},
+			},
+		},
+		"streamed": {
+			uploads: []Upload{
+				{Local: filepath.Join(dir, "input.csv"), Remote: "/in/input.csv"},
+				{Local: filepath.Join(dir, "large"), Remote: "/models", Dir: true},
+			},
+			wantVolumes: []corev1.Volume{
+				{Name: "jctl-upload", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+			},
+			wantMounts: []corev1.VolumeMount{
+				{Name: "jctl-upload", MountPath: "/in/input.csv", SubPath: "u0"},
+				{Name: "jctl-upload", MountPath: "/models", SubPath: "u1"},
+			},
+			wantInitImages: []string{DefaultUploaderImage},
+		},
+	}
+	for name, te := range tests {
+		u, err := NewUploads(te.uploads, "")
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", name, err)
+			continue
+		}
+		spec := BuildJob(JobOptions{Image: "example.com/batch", Uploads: u}, "default", 60).Spec.Template.Spec
+		if !reflect.DeepEqual(spec.Volumes, te.wantVolumes) {
+			t.Errorf("[%s] volumes got: %+v, want: %+v", name, spec.Volumes, te.wantVolumes)
+		}
+		if got := spec.Containers[0].VolumeMounts; !reflect.DeepEqual(got, te.wantMounts) {
+			t.Errorf("[%s] mounts got: %+v, want: %+v", name, got, te.wantMounts)
+		}
+		var images []string
+		for _, c := range spec.InitContainers {
+			images = append(images, c.Image)
+		}
+		if !reflect.DeepEqual(images, te.wantInitImages) {
+			t.Errorf("[%s] init containers got: %v, want: %v", name, images, te.wantInitImages)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/toshi0607/jctl/pkg/path"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// FileName is the name of the project configuration file, looked up at
+// the module root.
+const FileName = "jctl.yaml"
+
+type (
+	// File is the content of jctl.yaml. The top level settings are the
+	// defaults of every program and Commands overrides them per importpath.
+	//
+	//   namespace: batch
+	//   ttlSec: 600
+	//   env:
+	//     LOG_LEVEL: info
+	//   commands:
+	//     ./cmd/migrate:
+	//       args: ["--dry-run"]
+	//       resources:
+	//         requests:
+	//           memory: 256Mi
+	File struct {
+		Settings
+		Commands map[string]Settings `json:"commands,omitempty"`
+	}
+
+	// Settings are the parameters of a program run by jctl.
+	Settings struct {
+		Namespace        string                       `json:"namespace,omitempty"`
+		TimeoutSec       int                          `json:"timeoutSec,omitempty"`
+		TTLSec           *int32                       `json:"ttlSec,omitempty"`
+		Repo             string                       `json:"repo,omitempty"`
+		BaseImage        string                       `json:"baseImage,omitempty"`
+		Platforms        []string                     `json:"platforms,omitempty"`
+		Build            *BuildSettings               `json:"build,omitempty"`
+		Args             []string                     `json:"args,omitempty"`
+		Env              map[string]string            `json:"env,omitempty"`
+		EnvFromSecret    []string                     `json:"envFromSecret,omitempty"`
+		EnvFromConfigMap []string                     `json:"envFromConfigMap,omitempty"`
+		SecretEnv        map[string]string            `json:"secretEnv,omitempty"`
+		Resources        *corev1.ResourceRequirements `json:"resources,omitempty"`
+		Parallelism      *int32                       `json:"parallelism,omitempty"`
+		Completions      *int32                       `json:"completions,omitempty"`
+		CompletionMode   string                       `json:"completionMode,omitempty"`
+		BackoffLimit     *int32                       `json:"backoffLimit,omitempty"`
+		// ActiveDeadlineSec limits the run time of the Job on the cluster,
+		// retries included. TimeoutSec only limits how long jctl waits.
+		ActiveDeadlineSec *int64                    `json:"activeDeadlineSec,omitempty"`
+		RestartPolicy     string                    `json:"restartPolicy,omitempty"`
+		PodFailurePolicy  *batchv1.PodFailurePolicy `json:"podFailurePolicy,omitempty"`
+		ImagePullSecrets  []string                  `json:"imagePullSecrets,omitempty"`
+		// CreatePullSecret creates the first of ImagePullSecrets from the
+		// credentials used to push the image.
+		CreatePullSecret   *bool               `json:"createPullSecret,omitempty"`
+		ServiceAccountName string              `json:"serviceAccountName,omitempty"`
+		NodeSelector       map[string]string   `json:"nodeSelector,omitempty"`
+		Tolerations        []corev1.Toleration `json:"tolerations,omitempty"`
+		Affinity           *corev1.Affinity    `json:"affinity,omitempty"`
+		PriorityClassName  string              `json:"priorityClassName,omitempty"`
+		RuntimeClassName   string              `json:"runtimeClassName,omitempty"`
+		// Mounts are written like --mount, e.g. pvc:datasets:/data:ro.
+		Mounts []string `json:"mounts,omitempty"`
+		// Files and Dirs are written like --file and --dir. Local paths are
+		// relative to the current directory.
+		Files         []string `json:"files,omitempty"`
+		Dirs          []string `json:"dirs,omitempty"`
+		UploaderImage string   `json:"uploaderImage,omitempty"`
+		// Artifacts is written like --artifacts, e.g. /out=reports.
+		Artifacts string `json:"artifacts,omitempty"`
+	}
+
+	// BuildSettings are the flags and environment variables of go build.
+	// Ldflags, Gcflags and Env values may contain templates like
+	// {{.Git.ShortCommit}}.
+	BuildSettings struct {
+		Ldflags  string            `json:"ldflags,omitempty"`
+		Gcflags  string            `json:"gcflags,omitempty"`
+		Tags     []string          `json:"tags,omitempty"`
+		Trimpath *bool             `json:"trimpath,omitempty"`
+		Mod      string            `json:"mod,omitempty"`
+		Env      map[string]string `json:"env,omitempty"`
+	}
+)
+
+// Find loads the configuration file at p. When p is empty, jctl.yaml at
+// the module root is used if it exists. Keys of Commands starting with
+// "./" are resolved against the module path.
+func Find(p string) (*File, error) {
+	mod := path.ModInfo()
+	if p == "" {
+		if mod == nil {
+			return &File{}, nil
+		}
+		p = filepath.Join(mod.Dir, FileName)
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			return &File{}, nil
+		}
+	}
+
+	f, err := Load(p)
+	if err != nil {
+		return nil, err
+	}
+	if mod != nil {
+		commands := make(map[string]Settings, len(f.Commands))
+		for k, v := range f.Commands {
+			if k == "." || strings.HasPrefix(k, "./") {
+				k = mod.Path + strings.TrimPrefix(k, ".")
+			}
+			commands[k] = v
+		}
+		f.Commands = commands
+	}
+	return f, nil
+}
+
+// Load reads the configuration file at p.
+func Load(p string) (*File, error) {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read config file, path: %s", p)
+	}
+	var f File
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config file, path: %s", p)
+	}
+	return &f, nil
+}
+
+// For returns the top level settings overridden by the settings of importpath.
+func (f *File) For(importpath string) Settings {
+	return f.Settings.Merge(f.Commands[importpath])
+}
+
+// Merge returns s overridden by the non-zero fields of o. Maps are merged
+// key by key and lists of sources are appended.
+func (s Settings) Merge(o Settings) Settings {
+	if o.Namespace != "" {
+		s.Namespace = o.Namespace
+	}
+	if o.TimeoutSec != 0 {
+		s.TimeoutSec = o.TimeoutSec
+	}
+	if o.TTLSec != nil {
+		s.TTLSec = o.TTLSec
+	}
+	if o.Repo != "" {
+		s.Repo = o.Repo
+	}
+	if o.BaseImage != "" {
+		s.BaseImage = o.BaseImage
+	}
+	if o.Platforms != nil {
+		s.Platforms = o.Platforms
+	}
+	if o.Build != nil {
+		var b BuildSettings
+		if s.Build != nil {
+			b = *s.Build
+		}
+		b = b.Merge(*o.Build)
+		s.Build = &b
+	}
+	if o.Args != nil {
+		s.Args = o.Args
+	}
+	s.Env = mergeMap(s.Env, o.Env)
+	s.EnvFromSecret = appendUnique(s.EnvFromSecret, o.EnvFromSecret)
+	s.EnvFromConfigMap = appendUnique(s.EnvFromConfigMap, o.EnvFromConfigMap)
+	s.SecretEnv = mergeMap(s.SecretEnv, o.SecretEnv)
+	if o.Resources != nil {
+		s.Resources = mergeResources(s.Resources, o.Resources)
+	}
+	if o.Parallelism != nil {
+		s.Parallelism = o.Parallelism
+	}
+	if o.Completions != nil {
+		s.Completions = o.Completions
+	}
+	if o.CompletionMode != "" {
+		s.CompletionMode = o.CompletionMode
+	}
+	if o.BackoffLimit != nil {
+		s.BackoffLimit = o.BackoffLimit
+	}
+	if o.ActiveDeadlineSec != nil {
+		s.ActiveDeadlineSec = o.ActiveDeadlineSec
+	}
+	if o.RestartPolicy != "" {
+		s.RestartPolicy = o.RestartPolicy
+	}
+	if o.PodFailurePolicy != nil {
+		s.PodFailurePolicy = o.PodFailurePolicy
+	}
+	if o.ImagePullSecrets != nil {
+		s.ImagePullSecrets = o.ImagePullSecrets
+	}
+	if o.CreatePullSecret != nil {
+		s.CreatePullSecret = o.CreatePullSecret
+	}
+	if o.ServiceAccountName != "" {
+		s.ServiceAccountName = o.ServiceAccountName
+	}
+	s.NodeSelector = mergeMap(s.NodeSelector, o.NodeSelector)
+	if o.Tolerations != nil {
+		s.Tolerations = o.Tolerations
+	}
+	if o.Affinity != nil {
+		s.Affinity = o.Affinity
+	}
+	if o.PriorityClassName != "" {
+		s.PriorityClassName = o.PriorityClassName
+	}
+	if o.RuntimeClassName != "" {
+		s.RuntimeClassName = o.RuntimeClassName
+	}
+	s = s.mergeVolumes(o)
+	if o.UploaderImage != "" {
+		s.UploaderImage = o.UploaderImage
+	}
+	if o.Artifacts != "" {
+		s.Artifacts = o.Artifacts
+	}
+	return s
+}
+
+// Merge returns b overridden by the non-zero fields of o.
+func (b BuildSettings) Merge(o BuildSettings) BuildSettings {
+	if o.Ldflags != "" {
+		b.Ldflags = o.Ldflags
+	}
+	if o.Gcflags != "" {
+		b.Gcflags = o.Gcflags
+	}
+	if o.Tags != nil {
+		b.Tags = o.Tags
+	}
+	if o.Trimpath != nil {
+		b.Trimpath = o.Trimpath
+	}
+	if o.Mod != "" {
+		b.Mod = o.Mod
+	}
+	b.Env = mergeMap(b.Env, o.Env)
+	return b
+}
+
+func mergeMap(a, b map[string]string) map[string]string {
+	if len(b) == 0 {
+		return a
+	}
+	m := make(map[string]string, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+// mergeResources merges requests and limits resource by resource, so that
+// --cpu does not drop the memory request of jctl.yaml.
+func mergeResources(a, b *corev1.ResourceRequirements) *corev1.ResourceRequirements {
+	if a == nil {
+		return b
+	}
+	return &corev1.ResourceRequirements{
+		Requests: mergeResourceList(a.Requests, b.Requests),
+		Limits:   mergeResourceList(a.Limits, b.Limits),
+	}
+}
+
+func mergeResourceList(a, b corev1.ResourceList) corev1.ResourceList {
+	if len(b) == 0 {
+		return a
+	}
+	l := make(corev1.ResourceList, len(a)+len(b))
+	for k, v := range a {
+		l[k] = v
+	}
+	for k, v := range b {
+		l[k] = v
+	}
+	return l
+}
+
+// mergeVolumes appends the mounts, files and dirs of o to those of s. An
+// entry of o replaces the entries of s at the same path in the container,
+// whatever their kind, so that a flag wins over jctl.yaml.
+func (s Settings) mergeVolumes(o Settings) Settings {
+	replaced := make(map[string]bool)
+	for _, m := range o.Mounts {
+		replaced[mountPath(m)] = true
+	}
+	for _, u := range append(append([]string(nil), o.Files...), o.Dirs...) {
+		replaced[uploadPath(u)] = true
+	}
+	delete(replaced, "")
+	keep := func(l []string, target func(string) string) []string {
+		if len(replaced) == 0 {
+			return l
+		}
+		var kept []string
+		for _, v := range l {
+			if !replaced[target(v)] {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	}
+	s.Mounts = appendUnique(keep(s.Mounts, mountPath), o.Mounts)
+	s.Files = appendUnique(keep(s.Files, uploadPath), o.Files)
+	s.Dirs = appendUnique(keep(s.Dirs, uploadPath), o.Dirs)
+	return s
+}
+
+// mountPath returns the path in the container of a mount written like
+// --mount, or "" when it is malformed and left to be reported later.
+func mountPath(m string) string {
+	parts := strings.Split(m, ":")
+	switch {
+	case parts[0] == "emptydir" && len(parts) >= 2:
+		return cleanPath(parts[1])
+	case parts[0] != "emptydir" && len(parts) >= 3:
+		return cleanPath(parts[2])
+	}
+	return ""
+}
+
+// uploadPath returns the path in the container of an upload written like
+// --file or --dir, or "" when it is malformed.
+func uploadPath(u string) string {
+	i := strings.Index(u, "=")
+	if i < 0 {
+		return ""
+	}
+	return cleanPath(u[i+1:])
+}
+
+func cleanPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+func appendUnique(a, b []string) []string {
+	if len(b) == 0 {
+		return a
+	}
+	l := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, vs := range [][]string{a, b} {
+		for _, v := range vs {
+			if !seen[v] {
+				seen[v] = true
+				l = append(l, v)
+			}
+		}
+	}
+	return l
+}
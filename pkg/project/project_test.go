@@ -0,0 +1,159 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestFile_For(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, FileName)
+	content := `
+namespace: batch
+env:
+  A: a
+  B: b
+envFromSecret: [common]
+resources:
+  requests:
+    cpu: 100m
+    memory: 128Mi
+commands:
+  example.com/cmd/migrate:
+    namespace: migrate
+    args: ["--dry-run"]
+    env:
+      B: overridden
+    envFromSecret: [migrate]
+    resources:
+      requests:
+        memory: 1Gi
+      limits:
+        memory: 2Gi
+`
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		importpath string
+		want       Settings
+	}{
+		"without override": {
+			importpath: "example.com/cmd/other",
+			want: Settings{
+				Namespace:     "batch",
+				Env:           map[string]string{"A": "a", "B": "b"},
+				EnvFromSecret: []string{"common"},
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("128Mi"),
+					},
+				},
+			},
+		},
+		"with override": {
+			importpath: "example.com/cmd/migrate",
+			want: Settings{
+				Namespace:     "migrate",
+				Args:          []string{"--dry-run"},
+				Env:           map[string]string{"A": "a", "B": "overridden"},
+				EnvFromSecret: []string{"common", "migrate"},
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("100m"),
+						corev1.ResourceMemory: resource.MustParse("1Gi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceMemory: resource.MustParse("2Gi"),
+					},
+				},
+			},
+		},
+	}
+	for name, te := range tests {
+		got := f.For(te.importpath)
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
+
+func TestLoad_unknownField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, FileName)
+	if err := ioutil.WriteFile(p, []byte("namespac: typo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(p); err == nil {
+		t.Error("want error for unknown field")
+	}
+}
+
+func TestSettings_Merge_volumes(t *testing.T) {
+	file := Settings{
+		Mounts: []string{"pvc:datasets:/data:ro", "secret:creds:/etc/creds", "emptydir:/scratch"},
+		Files:  []string{"./input.csv=/in/input.csv"},
+		Dirs:   []string{"./fixtures=/fixtures"},
+	}
+	tests := map[string]struct {
+		flags Settings
+		want  Settings
+	}{
+		"no flags": {
+			want: file,
+		},
+		"new paths": {
+			flags: Settings{Mounts: []string{"pvc:results:/out"}, Files: []string{"./other.csv=/in/other.csv"}},
+			want: Settings{
+				Mounts: []string{"pvc:datasets:/data:ro", "secret:creds:/etc/creds", "emptydir:/scratch", "pvc:results:/out"},
+				Files:  []string{"./input.csv=/in/input.csv", "./other.csv=/in/other.csv"},
+				Dirs:   []string{"./fixtures=/fixtures"},
+			},
+		},
+		"same paths": {
+			flags: Settings{
+				Mounts: []string{"pvc:datasets-v2:/data/", "emptydir:/scratch:1Gi"},
+				Files:  []string{"./local.csv=/in/input.csv"},
+			},
+			want: Settings{
+				Mounts: []string{"secret:creds:/etc/creds", "pvc:datasets-v2:/data/", "emptydir:/scratch:1Gi"},
+				Files:  []string{"./local.csv=/in/input.csv"},
+				Dirs:   []string{"./fixtures=/fixtures"},
+			},
+		},
+		"other kind at the same path": {
+			flags: Settings{Dirs: []string{"./data=/data"}, Mounts: []string{"emptydir:/fixtures"}},
+			want: Settings{
+				Mounts: []string{"secret:creds:/etc/creds", "emptydir:/scratch", "emptydir:/fixtures"},
+				Files:  []string{"./input.csv=/in/input.csv"},
+				Dirs:   []string{"./data=/data"},
+			},
+		},
+	}
+	for name, te := range tests {
+		got := file.Merge(te.flags)
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", name, got, te.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package publish
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// dockerHubServer is the key of Docker Hub in ~/.docker/config.json,
+// which differs from the name of its registry.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+type (
+	dockerConfigJSON struct {
+		Auths map[string]dockerConfigEntry `json:"auths"`
+	}
+
+	dockerConfigEntry struct {
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+		Auth     string `json:"auth"`
+	}
+)
+
+// DockerConfigJSON returns the credentials which Publish uses in the
+// format of ~/.docker/config.json, the content of a Secret of type
+// kubernetes.io/dockerconfigjson.
+func (d *publisher) DockerConfigJSON() ([]byte, error) {
+	if d.auth == authn.Anonymous {
+		return nil, fmt.Errorf("no credentials found for %s", d.registry)
+	}
+	cfg, err := d.auth.Authorization()
+	if err != nil {
+		return nil, err
+	}
+
+	e := dockerConfigEntry{
+		Username: cfg.Username,
+		Password: cfg.Password,
+		Auth:     cfg.Auth,
+	}
+	switch {
+	case e.Auth != "":
+	case e.Username != "" || e.Password != "":
+		e.Auth = base64.StdEncoding.EncodeToString([]byte(e.Username + ":" + e.Password))
+	default:
+		// Kubelet only knows basic auth, not identity or registry tokens.
+		return nil, fmt.Errorf("credentials of %s have no username and password to pull images with", d.registry)
+	}
+
+	server := d.registry.Name()
+	if server == name.DefaultRegistry {
+		server = dockerHubServer
+	}
+	return json.Marshal(dockerConfigJSON{
+		Auths: map[string]dockerConfigEntry{server: e},
+	})
+}
@@ -0,0 +1,69 @@
+package publish
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+func TestPublisher_DockerConfigJSON(t *testing.T) {
+	tests := map[string]struct {
+		registry string
+		auth     authn.Authenticator
+		want     dockerConfigJSON
+		wantErr  bool
+	}{
+		"username and password": {
+			registry: "gcr.io",
+			auth:     authn.FromConfig(authn.AuthConfig{Username: "user", Password: "pass"}),
+			want: dockerConfigJSON{Auths: map[string]dockerConfigEntry{
+				"gcr.io": {Username: "user", Password: "pass", Auth: "dXNlcjpwYXNz"},
+			}},
+		},
+		"auth": {
+			registry: "ghcr.io",
+			auth:     authn.FromConfig(authn.AuthConfig{Auth: "dXNlcjpwYXNz"}),
+			want: dockerConfigJSON{Auths: map[string]dockerConfigEntry{
+				"ghcr.io": {Auth: "dXNlcjpwYXNz"},
+			}},
+		},
+		"docker hub": {
+			registry: name.DefaultRegistry,
+			auth:     &authn.Basic{Username: "user", Password: "pass"},
+			want: dockerConfigJSON{Auths: map[string]dockerConfigEntry{
+				"https://index.docker.io/v1/": {Username: "user", Password: "pass", Auth: "dXNlcjpwYXNz"},
+			}},
+		},
+		"anonymous":      {registry: "gcr.io", auth: authn.Anonymous, wantErr: true},
+		"registry token": {registry: "gcr.io", auth: authn.FromConfig(authn.AuthConfig{RegistryToken: "token"}), wantErr: true},
+	}
+	for n, te := range tests {
+		reg, err := name.NewRegistry(te.registry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &publisher{registry: reg, auth: te.auth}
+		b, err := p.DockerConfigJSON()
+		if te.wantErr {
+			if err == nil {
+				t.Errorf("[%s] want error, got: %s", n, b)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", n, err)
+			continue
+		}
+		var got dockerConfigJSON
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("[%s] unexpected error: %v", n, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, te.want) {
+			t.Errorf("[%s] got: %+v, want: %+v", n, got, te.want)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -16,10 +15,12 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/toshi0607/jctl/pkg/build"
 )
 
 type Publisher interface {
-	Publish(v1.Image, string) (name.Reference, error)
+	Publish(build.Result, string) (name.Reference, error)
+	DockerConfigJSON() ([]byte, error)
 }
 
 type Namer func(string) string
@@ -27,6 +28,7 @@ type Namer func(string) string
 type publisher struct {
 	log      *log.Logger
 	base     string
+	registry name.Registry
 	rt       http.RoundTripper
 	auth     authn.Authenticator
 	namer    Namer
@@ -35,11 +37,11 @@ type publisher struct {
 
 var defaultTag = "latest"
 
-func New(outStream io.Writer) (Publisher, error) {
+// New returns a Publisher which pushes images under repoName.
+func New(outStream io.Writer, repoName string) (Publisher, error) {
 	log := log.New(outStream, "publish: ", log.LstdFlags)
-	repoName := os.Getenv("JCTL_DOCKER_REPO")
 	if repoName == "" {
-		return nil, errors.New("JCTL_DOCKER_REPO environment variable is required")
+		return nil, errors.New("JCTL_DOCKER_REPO environment variable or repo in jctl.yaml is required")
 	}
 	repo, err := name.NewRepository(repoName)
 	if err != nil {
@@ -54,16 +56,17 @@ func New(outStream io.Writer) (Publisher, error) {
 	}
 
 	return &publisher{
-		log:   log,
-		base:  repoName,
-		rt:    http.DefaultTransport,
-		auth:  auth,
-		namer: packageWithMD5,
+		log:      log,
+		base:     repoName,
+		registry: repo.Registry,
+		rt:       http.DefaultTransport,
+		auth:     auth,
+		namer:    packageWithMD5,
 	}, nil
 
 }
 
-func (d *publisher) Publish(img v1.Image, path string) (name.Reference, error) {
+func (d *publisher) Publish(img build.Result, path string) (name.Reference, error) {
 	path = strings.ToLower(path)
 
 	var os []name.Option
@@ -75,21 +78,52 @@ func (d *publisher) Publish(img v1.Image, path string) (name.Reference, error) {
 		return nil, err
 	}
 
-	if err := remote.Write(tag, img, remote.WithAuth(d.auth), remote.WithTransport(d.rt)); err != nil {
-		return nil, err
-	}
-
 	h, err := img.Digest()
 	if err != nil {
 		return nil, err
 	}
-	dig, err := name.NewDigest(fmt.Sprintf("%s/%s@%s", d.base, d.namer(path), h))
+	dig, err := name.NewDigest(fmt.Sprintf("%s/%s@%s", d.base, d.namer(path), h), os...)
 	if err != nil {
 		return nil, err
 	}
+
+	opts := []remote.Option{remote.WithAuth(d.auth), remote.WithTransport(d.rt)}
+	if d.exists(tag, h, opts) {
+		d.log.Printf("image up to date, digest: %s", h)
+		return &dig, nil
+	}
+	// A manifest found by digest was pushed with all of its blobs before,
+	// so only the tag has to be moved back to it.
+	if d.exists(dig, h, opts) {
+		if err := remote.Tag(tag, img, opts...); err != nil {
+			return nil, err
+		}
+		d.log.Printf("image up to date, digest: %s, tagged: %s", h, tag.TagStr())
+		return &dig, nil
+	}
+
+	switch i := img.(type) {
+	case v1.ImageIndex:
+		if err := remote.WriteIndex(tag, i, opts...); err != nil {
+			return nil, err
+		}
+	case v1.Image:
+		if err := remote.Write(tag, i, opts...); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported build result %T", img)
+	}
 	return &dig, nil
 }
 
+// exists reports whether ref points at the manifest of digest h. Any error,
+// like a missing repository, is taken as no and leaves it to the push.
+func (d *publisher) exists(ref name.Reference, h v1.Hash, opts []remote.Option) bool {
+	desc, err := remote.Head(ref, opts...)
+	return err == nil && desc.Digest == h
+}
+
 func packageWithMD5(importpath string) string {
 	hasher := md5.New()
 	hasher.Write([]byte(importpath))
@@ -0,0 +1,83 @@
+package publish
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestPublish_upToDate(t *testing.T) {
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(&bytes.Buffer{}, "", 0))))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	p := &publisher{
+		log:   log.New(&out, "", 0),
+		base:  u.Host + "/jctl",
+		rt:    http.DefaultTransport,
+		auth:  authn.Anonymous,
+		namer: packageWithMD5,
+	}
+	const path = "github.com/toshi0607/jctl/testdata/cmd/hello_world"
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := p.Publish(img, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "up to date") {
+		t.Errorf("first publish should push, got: %s", out.String())
+	}
+
+	out.Reset()
+	if _, err := p.Publish(img, path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "image up to date") {
+		t.Errorf("second publish should be skipped, got: %s", out.String())
+	}
+
+	// Once latest moved to another image, publishing the first one again
+	// only tags it.
+	if _, err := p.Publish(other, path); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if _, err := p.Publish(img, path); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "tagged: latest") {
+		t.Errorf("publish should retag, got: %s", out.String())
+	}
+	tag, err := name.NewTag(ref.Context().String() + ":latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := remote.Head(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ref.Identifier(); desc.Digest.String() != want {
+		t.Errorf("latest got: %s, want: %s", desc.Digest, want)
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Printf("JCTL_GREETING=%s\n", os.Getenv("JCTL_GREETING"))
+}